@@ -19,20 +19,108 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/bluefermion/feedback/internal/handler"
+	"github.com/bluefermion/feedback/internal/metrics"
 	"github.com/bluefermion/feedback/internal/repository"
+	"github.com/bluefermion/feedback/internal/repository/migrations"
+	"github.com/bluefermion/feedback/internal/selfhealing"
 	"github.com/joho/godotenv"
 )
 
-// main is the entry point of the application.
-// It orchestrates the startup sequence: config -> db -> handlers -> router -> server.
+// shuttingDown flips to true as soon as a shutdown signal is received, so
+// handleHealth can tell orchestrators to stop routing new traffic here while
+// the server drains in-flight requests and self-healing jobs.
+var shuttingDown atomic.Bool
+
+// main dispatches to the `migrate` CLI subcommand or, by default, starts the
+// HTTP server.
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+	runServer()
+}
+
+// runMigrate implements `feedback migrate up|down|status|fts-rebuild`,
+// letting operators apply or inspect schema migrations independently of
+// process start (e.g. before a rolling deploy).
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatalf("usage: feedback migrate <up|down|status|fts-rebuild>")
+	}
+
+	dbPath := os.Getenv("FEEDBACK_DB_PATH")
+	if dbPath == "" {
+		dbPath = "feedback.db"
+	}
+
+	// fts-rebuild needs the full repository (for RebuildFTS), not just the
+	// raw migrator, so it's handled separately.
+	if args[0] == "fts-rebuild" {
+		repo, err := repository.NewSQLiteRepository(dbPath)
+		if err != nil {
+			log.Fatalf("Failed to initialize database: %v", err)
+		}
+		defer repo.Close()
+		if err := repo.RebuildFTS(); err != nil {
+			log.Fatalf("fts-rebuild failed: %v", err)
+		}
+		log.Printf("FTS index rebuilt")
+		return
+	}
+
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	migrator := migrations.NewMigrator(db)
+
+	switch args[0] {
+	case "up":
+		if err := migrator.Up(); err != nil {
+			log.Fatalf("migrate up failed: %v", err)
+		}
+		log.Printf("migrations applied")
+	case "down":
+		if err := migrator.Down(); err != nil {
+			log.Fatalf("migrate down failed: %v", err)
+		}
+		log.Printf("migration reverted")
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalf("migrate status failed: %v", err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			log.Printf("%04d_%s: %s", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatalf("unknown migrate subcommand %q (want up|down|status|fts-rebuild)", args[0])
+	}
+}
+
+// runServer is the entry point of the application.
+// It orchestrates the startup sequence: config -> db -> handlers -> router -> server.
+func runServer() {
 	// -------------------------------------------------------------------------
 	// 1. CONFIGURATION LOADING
 	// -------------------------------------------------------------------------
@@ -88,15 +176,40 @@ func main() {
 	// Using os.DirFS allows us to load templates from the disk in development.
 	// In production, we could swap this with embed.FS without changing the handler logic.
 	templateFS := os.DirFS("templates")
-	feedbackHandler := handler.NewFeedbackHandler(repo, templateFS)
+	metricsCollector := metrics.NewPrometheusCollector()
+	// accessLogger is shared between the handler (audit events for
+	// submission/self-healing) and newStructuredLoggingMiddleware (per-request
+	// access log lines below), so the two logs correlate by request_id.
+	accessLogger := slog.New(slog.NewJSONHandler(os.Stderr, nil))
+	feedbackHandler := handler.NewFeedbackHandler(repo, templateFS, metricsCollector, accessLogger)
 
 	// Initialize the error handler with OpenCode self-healing integration.
 	// This provides beautiful error pages and triggers automated analysis.
 	openCodeAnalyzer := handler.NewOpenCodeAnalyzer(handler.OpenCodeConfig{
 		ContainerName: "opencode-selfhealing",
 		Enabled:       os.Getenv("OPENCODE_ENABLED") == "true",
+		Collector:     metricsCollector,
+	})
+	// The webhook analyzer notifies an external endpoint in parallel with
+	// (or instead of) the OpenCode container. Deliveries that exhaust their
+	// retry budget land in the feedback_webhook_dlq table, browsable via
+	// /admin/webhook-dlq.
+	webhookAnalyzer := handler.NewWebhookAnalyzer(handler.WebhookConfig{
+		SignRequests:   os.Getenv("OPENCODE_WEBHOOK_SIGN") == "true",
+		DeadLetterSink: repo,
+		Collector:      metricsCollector,
+	})
+	compositeAnalyzer := handler.NewCompositeAnalyzer(openCodeAnalyzer, webhookAnalyzer)
+	// Wrap the analyzer so a hot error path can't flood OpenCode with one
+	// analysis job per request: duplicate fingerprints within the dedup
+	// window are coalesced, and dispatch is rate-limited through a bounded
+	// worker pool.
+	dispatchedAnalyzer := handler.NewAnalyzerDispatcher(compositeAnalyzer, handler.DispatcherConfig{
+		DedupWindow: 5 * time.Minute,
 	})
-	errorHandler := handler.NewErrorHandler(templateFS, openCodeAnalyzer)
+	errorReporter := handler.NewSlogReporter(os.Stderr)
+	errorHandler := handler.NewErrorHandler(templateFS, dispatchedAnalyzer, errorReporter, repo)
+	webhookDLQHandler := handler.NewAdminWebhookDLQHandler(repo, webhookAnalyzer)
 
 	// -------------------------------------------------------------------------
 	// 3. ROUTER SETUP
@@ -111,7 +224,9 @@ func main() {
 	// Root endpoint: identifies the service. Good for sanity checks.
 	mux.HandleFunc("GET /", handleRoot)
 	// Health check: used by load balancers or orchestrators (like K8s) to know if the app is alive.
-	mux.HandleFunc("GET /health", handleHealth)
+	mux.HandleFunc("GET /health", handleHealth(feedbackHandler.SelfHealingTrigger()))
+	// Prometheus scrape endpoint: HTTP and self-healing metrics.
+	mux.HandleFunc("GET /metrics", metricsCollector.Handler())
 
 	// -- API Routes (JSON) --
 
@@ -119,10 +234,17 @@ func main() {
 	mux.HandleFunc("POST /api/feedback", feedbackHandler.HandleSubmit)
 	// List feedback. RESTful convention: GET on collection retrieves list.
 	mux.HandleFunc("GET /api/feedback", feedbackHandler.HandleList)
+	// Full-text search over feedback (title/description/console logs/analysis/journey).
+	mux.HandleFunc("GET /api/feedback/search", feedbackHandler.HandleSearch)
 	// Get single feedback. RESTful convention: GET on specific ID.
 	mux.HandleFunc("GET /api/feedback/{id}", feedbackHandler.HandleGet)
+	// Server-Sent Events stream of a feedback's self-healing job progress.
+	mux.HandleFunc("GET /api/feedback/{id}/stream", feedbackHandler.HandleSelfHealingStream)
 	// Status endpoint for the self-healing system (showing background worker state).
 	mux.HandleFunc("GET /api/selfhealing/status", feedbackHandler.HandleSelfHealingStatus)
+	// Browse/inspect the durable job queue backing self-healing.
+	mux.HandleFunc("GET /api/selfhealing/jobs", feedbackHandler.HandleJobsList)
+	mux.HandleFunc("GET /api/selfhealing/jobs/{id}", feedbackHandler.HandleJobDetail)
 
 	// -- Static Assets --
 
@@ -144,14 +266,33 @@ func main() {
 	mux.HandleFunc("GET /error", errorHandler.HandleErrorPreview)
 	mux.HandleFunc("GET /error/trigger", errorHandler.HandleFakeError)
 
+	// Admin: browse captured errors and replay their analysis.
+	mux.HandleFunc("GET /admin/errors", errorHandler.HandleAdminErrorsList)
+	mux.HandleFunc("GET /admin/errors/{id}", errorHandler.HandleAdminErrorDetail)
+	mux.HandleFunc("POST /admin/errors/{id}/replay", errorHandler.HandleAdminErrorReplay)
+
+	// Admin: browse dead-lettered webhook deliveries and replay them.
+	mux.HandleFunc("GET /admin/webhook-dlq", webhookDLQHandler.HandleList)
+	mux.HandleFunc("GET /admin/webhook-dlq/{id}", webhookDLQHandler.HandleDetail)
+	mux.HandleFunc("POST /admin/webhook-dlq/{id}/replay", webhookDLQHandler.HandleReplay)
+
 	// -------------------------------------------------------------------------
 	// 4. MIDDLEWARE & SERVER START
 	// -------------------------------------------------------------------------
 
-	// Wrap the mux in our logging middleware.
-	// Middleware pattern: Intercepts every request to perform cross-cutting concerns
-	// (logging, auth, metrics) before passing control to the actual handler.
-	handler := loggingMiddleware(mux)
+	// requestIDMiddleware goes outermost, ahead of panic recovery, so the
+	// request ID is already on the context by the time a panic is recovered
+	// and handed to errorHandler - it does nothing risky enough to need
+	// recovery itself. Panic recovery then wraps structured logging so a
+	// panic anywhere downstream (including inside logging) still gets the
+	// beautiful error page and structured reporting instead of net/http's
+	// default "connection reset" behavior.
+	handler := requestIDMiddleware(errorHandler.PanicRecoveryMiddleware(newStructuredLoggingMiddleware(accessLogger, metricsCollector)(mux)))
+
+	srv := &http.Server{
+		Addr:    ":" + port,
+		Handler: handler,
+	}
 
 	// Log helpful information for the developer.
 	log.Printf("Starting server on :%s", port)
@@ -160,11 +301,60 @@ func main() {
 	log.Printf("Feedback list: http://localhost:%s/feedback", port)
 	log.Printf("Error page preview: http://localhost:%s/error", port)
 
-	// Start the blocking server loop.
-	// ListenAndServe will only return if there's an error (like port already in use).
-	if err := http.ListenAndServe(":"+port, handler); err != nil {
-		log.Fatalf("Server failed: %v", err)
+	// Run the server in the background so the main goroutine is free to wait
+	// on either a listen error or a termination signal, whichever comes first.
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-serveErr:
+		if err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Server failed: %v", err)
+		}
+	case <-ctx.Done():
+		shuttingDown.Store(true)
+		log.Printf("Shutdown signal received, draining (timeout %v)", shutdownTimeout())
+
+		drainCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout())
+		defer cancel()
+
+		// Stop accepting new connections and wait for in-flight HTTP requests
+		// to finish before touching the self-healing trigger, since a request
+		// still in HandleSubmit could still be about to call TriggerAsync.
+		if err := srv.Shutdown(drainCtx); err != nil {
+			log.Printf("HTTP server did not shut down cleanly: %v", err)
+		}
+
+		if trigger := feedbackHandler.SelfHealingTrigger(); trigger != nil {
+			if err := trigger.Wait(drainCtx); err != nil {
+				log.Printf("Self-healing job did not finish before drain timeout, cancelled it: %v", err)
+			}
+		}
+
+		log.Printf("Shutdown complete")
+	}
+}
+
+// shutdownTimeout bounds how long graceful shutdown waits for in-flight HTTP
+// requests and self-healing jobs to finish before forcing them closed.
+// Configurable since "drain a running LLM analysis" and "drain a quick JSON
+// handler" want very different budgets depending on deployment.
+func shutdownTimeout() time.Duration {
+	v := os.Getenv("SHUTDOWN_TIMEOUT")
+	if v == "" {
+		return 30 * time.Second
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.Printf("Invalid SHUTDOWN_TIMEOUT %q, using default 30s: %v", v, err)
+		return 30 * time.Second
 	}
+	return d
 }
 
 // handleRoot returns basic metadata about the service.
@@ -178,26 +368,28 @@ func handleRoot(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleHealth is a standard health check endpoint.
-// In a real deployment, this might check DB connectivity before returning "healthy".
-func handleHealth(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{
-		"status": "healthy",
-	})
-}
+// handleHealth is a standard health check endpoint. It reports "terminating"
+// with a 503 once shutdown has begun, so orchestrators stop routing new
+// traffic here while in-flight requests and self-healing jobs drain, and
+// surfaces how many self-healing jobs are currently running so operators can
+// tell why a drain is taking a while.
+func handleHealth(trigger *selfhealing.Trigger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
 
-// loggingMiddleware logs the HTTP method, path, and duration of every request.
-//
-// EDUCATIONAL CONTEXT:
-// Middleware in Go is typically a function that takes an http.Handler and returns an http.Handler.
-// It wraps the 'next' handler, allowing code to run before and after the inner handler.
-func loggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		// Call the next handler in the chain
-		next.ServeHTTP(w, r)
-		// Log after the request has been served
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
-	})
+		status := "healthy"
+		statusCode := http.StatusOK
+		if shuttingDown.Load() {
+			status = "terminating"
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		resp := map[string]interface{}{"status": status}
+		if trigger != nil {
+			resp["selfhealing_active_jobs"] = trigger.Status()["active_jobs"]
+		}
+
+		w.WriteHeader(statusCode)
+		json.NewEncoder(w).Encode(resp)
+	}
 }
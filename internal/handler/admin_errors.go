@@ -0,0 +1,130 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// admin_errors.go exposes the errors table captured by HandleError as a
+// small triage API: browse captured errors, inspect one, and replay its
+// analysis.
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// HandleAdminErrorsList processes GET /admin/errors (Pagination).
+func (h *ErrorHandler) HandleAdminErrorsList(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.writeAdminError(w, http.StatusServiceUnavailable, "error store not configured")
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	errs, err := h.store.ListErrors(limit, offset)
+	if err != nil {
+		log.Printf("Failed to list errors: %v", err)
+		h.writeAdminError(w, http.StatusInternalServerError, "failed to list errors")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(errs)
+}
+
+// HandleAdminErrorDetail processes GET /admin/errors/{id}.
+// {id} is the public error_id shown on the pretty error page.
+func (h *ErrorHandler) HandleAdminErrorDetail(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.writeAdminError(w, http.StatusServiceUnavailable, "error store not configured")
+		return
+	}
+
+	errorID := r.PathValue("id")
+	stored, err := h.store.GetErrorByID(errorID)
+	if err != nil {
+		log.Printf("Failed to get error %s: %v", errorID, err)
+		h.writeAdminError(w, http.StatusInternalServerError, "failed to retrieve error")
+		return
+	}
+	if stored == nil {
+		h.writeAdminError(w, http.StatusNotFound, "error not found")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stored)
+}
+
+// HandleAdminErrorReplay processes POST /admin/errors/{id}/replay.
+// It re-runs analysis for a previously captured error using the same
+// analyzer that HandleError dispatches to.
+func (h *ErrorHandler) HandleAdminErrorReplay(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.writeAdminError(w, http.StatusServiceUnavailable, "error store not configured")
+		return
+	}
+	if h.analyzer == nil {
+		h.writeAdminError(w, http.StatusServiceUnavailable, "no analyzer configured")
+		return
+	}
+
+	errorID := r.PathValue("id")
+	stored, err := h.store.GetErrorByID(errorID)
+	if err != nil {
+		log.Printf("Failed to get error %s: %v", errorID, err)
+		h.writeAdminError(w, http.StatusInternalServerError, "failed to retrieve error")
+		return
+	}
+	if stored == nil {
+		h.writeAdminError(w, http.StatusNotFound, "error not found")
+		return
+	}
+
+	errCtx := ErrorContext{
+		ErrorID:       stored.ErrorID,
+		TraceID:       stored.TraceID,
+		Fingerprint:   stored.Fingerprint,
+		ErrorMessage:  stored.ErrorMessage,
+		StackTrace:    stored.StackTrace,
+		RequestPath:   stored.RequestPath,
+		RequestMethod: stored.RequestMethod,
+		HandlerFile:   stored.HandlerFile,
+		HandlerFunc:   stored.HandlerFunc,
+		StatusCode:    stored.StatusCode,
+		Timestamp:     stored.CreatedAt,
+		UserAgent:     stored.UserAgent,
+	}
+
+	if err := h.analyzer.AnalyzeError(r.Context(), errCtx); err != nil {
+		log.Printf("[%s] Replay analysis failed to dispatch: %v", errorID, err)
+		h.writeAdminError(w, http.StatusInternalServerError, "failed to trigger analysis")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "analysis re-triggered",
+		"errorId": errorID,
+	})
+}
+
+// writeAdminError standardizes error responses for the admin errors API.
+func (h *ErrorHandler) writeAdminError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.ErrorResponse{Error: message})
+}
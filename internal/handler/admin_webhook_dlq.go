@@ -0,0 +1,147 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// admin_webhook_dlq.go exposes WebhookAnalyzer's dead-lettered payloads
+// (see DeadLetterSink) as a small triage API: browse them, inspect one,
+// and replay its delivery.
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// WebhookDeadLetterStore is the subset of repository.SQLiteRepository
+// HandleAdminWebhookDLQ* needs to browse and replay dead-lettered webhooks.
+type WebhookDeadLetterStore interface {
+	DeadLetterSink
+	GetWebhookDeadLetterByID(id int64) (*model.WebhookDeadLetter, error)
+	ListWebhookDeadLetters(limit, offset int) ([]*model.WebhookDeadLetter, error)
+	MarkWebhookDeadLetterReplayed(id int64) error
+}
+
+// AdminWebhookDLQHandler serves the /admin/webhook-dlq* endpoints.
+type AdminWebhookDLQHandler struct {
+	store    WebhookDeadLetterStore
+	webhooks *WebhookAnalyzer
+}
+
+// NewAdminWebhookDLQHandler creates a new AdminWebhookDLQHandler. webhooks
+// is used to redeliver a dead-lettered payload on replay; it may be nil,
+// in which case replay is disabled but browsing still works.
+func NewAdminWebhookDLQHandler(store WebhookDeadLetterStore, webhooks *WebhookAnalyzer) *AdminWebhookDLQHandler {
+	return &AdminWebhookDLQHandler{store: store, webhooks: webhooks}
+}
+
+// HandleList processes GET /admin/webhook-dlq (Pagination).
+func (h *AdminWebhookDLQHandler) HandleList(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "webhook dead-letter store not configured")
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	dls, err := h.store.ListWebhookDeadLetters(limit, offset)
+	if err != nil {
+		log.Printf("Failed to list webhook dead letters: %v", err)
+		h.writeError(w, http.StatusInternalServerError, "failed to list webhook dead letters")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dls)
+}
+
+// HandleDetail processes GET /admin/webhook-dlq/{id}.
+func (h *AdminWebhookDLQHandler) HandleDetail(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "webhook dead-letter store not configured")
+		return
+	}
+
+	dl, err := h.getByPathID(w, r)
+	if err != nil || dl == nil {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dl)
+}
+
+// HandleReplay processes POST /admin/webhook-dlq/{id}/replay. It redelivers
+// the dead-lettered payload through the same retry-and-dead-letter path as
+// a fresh webhook, so a second failure is re-recorded rather than lost.
+func (h *AdminWebhookDLQHandler) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	if h.store == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "webhook dead-letter store not configured")
+		return
+	}
+	if h.webhooks == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "no webhook analyzer configured")
+		return
+	}
+
+	dl, err := h.getByPathID(w, r)
+	if err != nil || dl == nil {
+		return
+	}
+
+	if err := h.store.MarkWebhookDeadLetterReplayed(dl.ID); err != nil {
+		log.Printf("Failed to mark webhook dead letter %d replayed: %v", dl.ID, err)
+		h.writeError(w, http.StatusInternalServerError, "failed to mark dead letter replayed")
+		return
+	}
+
+	go h.webhooks.sendWebhook(dl.ErrorID, []byte(dl.PayloadJSON))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "webhook replay triggered",
+		"id":      dl.ID,
+	})
+}
+
+// getByPathID loads the dead letter named by the {id} path value, writing
+// an error response and returning a nil dl if anything went wrong.
+func (h *AdminWebhookDLQHandler) getByPathID(w http.ResponseWriter, r *http.Request) (*model.WebhookDeadLetter, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "invalid id")
+		return nil, err
+	}
+
+	dl, err := h.store.GetWebhookDeadLetterByID(id)
+	if err != nil {
+		log.Printf("Failed to get webhook dead letter %d: %v", id, err)
+		h.writeError(w, http.StatusInternalServerError, "failed to retrieve webhook dead letter")
+		return nil, err
+	}
+	if dl == nil {
+		h.writeError(w, http.StatusNotFound, "webhook dead letter not found")
+		return nil, nil
+	}
+
+	return dl, nil
+}
+
+// writeError standardizes error responses for the webhook dead-letter admin API.
+func (h *AdminWebhookDLQHandler) writeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(model.ErrorResponse{Error: message})
+}
@@ -0,0 +1,189 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// analyzer_dispatcher.go wraps an ErrorAnalyzer with deduplication and rate
+// limiting so a hot path erroring on every request can't flood
+// OpenCode/the LLM with one analysis job per request.
+package handler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// AnalyzerDispatcher decorates an ErrorAnalyzer with:
+//  1. Fingerprint-based deduplication: the same underlying failure only
+//     triggers one analysis job per dedup window.
+//  2. A token-bucket rate limit on jobs actually reaching the analyzer.
+//  3. A bounded worker pool, so dispatch never spawns unbounded goroutines.
+type AnalyzerDispatcher struct {
+	next        ErrorAnalyzer
+	limiter     *rate.Limiter
+	dedupWindow time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time // fingerprint -> last dispatch time
+
+	jobs chan ErrorContext
+}
+
+// DispatcherConfig configures an AnalyzerDispatcher.
+type DispatcherConfig struct {
+	DedupWindow time.Duration // coalesce duplicate fingerprints within this window
+	RateLimit   rate.Limit    // sustained jobs/sec allowed through to the analyzer
+	Burst       int           // token bucket burst size
+	Workers     int           // size of the bounded worker pool
+	QueueSize   int           // job queue depth before dispatch is rejected
+}
+
+// NewAnalyzerDispatcher wraps next with dedup/rate-limit/worker-pool behavior.
+func NewAnalyzerDispatcher(next ErrorAnalyzer, config DispatcherConfig) *AnalyzerDispatcher {
+	if config.DedupWindow <= 0 {
+		config.DedupWindow = 5 * time.Minute
+	}
+	if config.RateLimit <= 0 {
+		config.RateLimit = rate.Every(time.Second)
+	}
+	if config.Burst <= 0 {
+		config.Burst = 1
+	}
+	if config.Workers <= 0 {
+		config.Workers = 2
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = 64
+	}
+
+	d := &AnalyzerDispatcher{
+		next:        next,
+		limiter:     rate.NewLimiter(config.RateLimit, config.Burst),
+		dedupWindow: config.DedupWindow,
+		seen:        make(map[string]time.Time),
+		jobs:        make(chan ErrorContext, config.QueueSize),
+	}
+
+	for i := 0; i < config.Workers; i++ {
+		go d.worker()
+	}
+
+	go d.cleanupLoop()
+
+	return d
+}
+
+// Fingerprint computes a stable identifier for the underlying failure behind
+// an error, independent of request-specific details. It is persisted
+// alongside the error record (model.StoredError.Fingerprint) so repeated
+// incidents can be counted, and used here to dedupe dispatch.
+func Fingerprint(handlerFile, handlerFunc, errorMessage, stackTrace string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s|%s|%s|%s",
+		handlerFile, handlerFunc, normalizeErrorMessage(errorMessage), stackTop(stackTrace))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// normalizeErrorMessage collapses runs of digits so the same failure
+// fingerprints the same way even when the message embeds request-specific
+// numbers (IDs, ports, byte counts, ...).
+func normalizeErrorMessage(msg string) string {
+	var b strings.Builder
+	prevDigit := false
+	for _, r := range msg {
+		if r >= '0' && r <= '9' {
+			if !prevDigit {
+				b.WriteRune('#')
+			}
+			prevDigit = true
+			continue
+		}
+		prevDigit = false
+		b.WriteRune(r)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// stackTop returns the first non-empty line of a stack trace, which is
+// typically what differentiates otherwise-identical errors.
+func stackTop(stackTrace string) string {
+	for _, line := range strings.Split(stackTrace, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// AnalyzeError implements ErrorAnalyzer. It fingerprints the error (if not
+// already set by the caller), coalesces duplicates within the dedup window,
+// and enqueues the job on the bounded worker pool. The incoming ctx is not
+// threaded to the queued job: dispatch is decoupled from the caller by
+// design (the job may run long after this call returns), so each worker
+// derives its own context for the wrapped analyzer instead.
+func (d *AnalyzerDispatcher) AnalyzeError(ctx context.Context, errCtx ErrorContext) error {
+	if errCtx.Fingerprint == "" {
+		errCtx.Fingerprint = Fingerprint(errCtx.HandlerFile, errCtx.HandlerFunc, errCtx.ErrorMessage, errCtx.StackTrace)
+	}
+
+	d.mu.Lock()
+	if last, ok := d.seen[errCtx.Fingerprint]; ok && time.Since(last) < d.dedupWindow {
+		d.mu.Unlock()
+		log.Printf("[%s] analysis dispatch coalesced (fingerprint %s, last dispatched %v ago)",
+			errCtx.ErrorID, errCtx.Fingerprint, time.Since(last).Round(time.Second))
+		return nil
+	}
+	d.seen[errCtx.Fingerprint] = time.Now()
+	d.mu.Unlock()
+
+	select {
+	case d.jobs <- errCtx:
+		log.Printf("[%s] analysis job queued (fingerprint %s, queue depth %d)", errCtx.ErrorID, errCtx.Fingerprint, d.QueueDepth())
+		return nil
+	default:
+		return fmt.Errorf("analyzer dispatch queue full (depth %d)", cap(d.jobs))
+	}
+}
+
+// worker drains the job queue, applying the rate limiter before calling the
+// wrapped analyzer.
+func (d *AnalyzerDispatcher) worker() {
+	for errCtx := range d.jobs {
+		if err := d.limiter.Wait(context.Background()); err != nil {
+			log.Printf("[%s] rate limiter wait failed: %v", errCtx.ErrorID, err)
+			continue
+		}
+		if err := d.next.AnalyzeError(context.Background(), errCtx); err != nil {
+			log.Printf("[%s] analyzer failed: %v", errCtx.ErrorID, err)
+		}
+	}
+}
+
+// QueueDepth reports the number of jobs currently waiting to be dispatched.
+func (d *AnalyzerDispatcher) QueueDepth() int {
+	return len(d.jobs)
+}
+
+// cleanupLoop evicts seen entries once they've fallen out of dedupWindow, so
+// the map doesn't retain one entry per distinct fingerprint ever seen for
+// the life of the process. Mirrors ipRateLimiter.cleanupLoop.
+func (d *AnalyzerDispatcher) cleanupLoop() {
+	ticker := time.NewTicker(d.dedupWindow)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-d.dedupWindow)
+		d.mu.Lock()
+		for fingerprint, last := range d.seen {
+			if last.Before(cutoff) {
+				delete(d.seen, fingerprint)
+			}
+		}
+		d.mu.Unlock()
+	}
+}
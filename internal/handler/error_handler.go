@@ -5,16 +5,21 @@
 package handler
 
 import (
-	"bytes"
+	"context"
 	"crypto/rand"
+	"encoding/base64"
 	"encoding/hex"
 	"fmt"
 	"html/template"
 	"io/fs"
 	"log"
 	"net/http"
+	"runtime/debug"
 	"sync"
 	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
+	"github.com/bluefermion/feedback/internal/requestid"
 )
 
 var (
@@ -28,40 +33,89 @@ type ErrorPageData struct {
 	ErrorID      string
 	ErrorMessage string
 	ImageURL     string
+	// Nonce is a per-request random value the page's CSP header is scoped
+	// to, so the template can tag its inline <style>/<script> tags with
+	// nonce="{{.Nonce}}" instead of the page needing 'unsafe-inline'.
+	Nonce string
 }
 
+// stackTraceContextKey is used by PanicRecoveryMiddleware to hand HandleError
+// the stack trace it captured inside its recover()ing deferred call, which
+// still includes the panicking frame. HandleError only captures its own
+// stack when no such trace is on the request context.
+type stackTraceContextKey struct{}
+
 // ErrorHandler handles errors with the beautiful error page and OpenCode integration
 type ErrorHandler struct {
 	templateFS fs.FS
 	analyzer   ErrorAnalyzer
+	reporter   ErrorReporter
+	store      ErrorStore
+}
+
+// ErrorStore persists captured errors so operators can browse, inspect, and
+// replay analysis for past incidents instead of the error ID being a dead
+// end once the response is written. repository.SQLiteRepository satisfies
+// this interface.
+type ErrorStore interface {
+	CreateError(e *model.StoredError) (int64, error)
+	GetErrorByID(errorID string) (*model.StoredError, error)
+	ListErrors(limit, offset int) ([]*model.StoredError, error)
+	UpdateErrorAnalysis(errorID, analysis string) error
+	MarkResolved(errorID string) error
 }
 
 // ErrorAnalyzer is the interface for triggering error analysis (OpenCode integration)
 type ErrorAnalyzer interface {
-	// AnalyzeError triggers async analysis of an error
-	// Returns immediately, analysis happens in background
-	AnalyzeError(ctx ErrorContext) error
+	// AnalyzeError triggers async analysis of an error. Returns immediately;
+	// analysis happens in background. ctx bounds only the synchronous part
+	// of the call (e.g. OpenCodeAnalyzer's container preflight); the
+	// background work runs on each implementation's own timeout and is not
+	// cancelled through ctx.
+	AnalyzeError(ctx context.Context, errCtx ErrorContext) error
 }
 
 // ErrorContext contains all context needed for error analysis
 type ErrorContext struct {
 	ErrorID       string
+	TraceID       string
 	ErrorMessage  string
 	StackTrace    string
 	RequestPath   string
 	RequestMethod string
 	HandlerFile   string
 	HandlerFunc   string
+	StatusCode    int
 	Timestamp     time.Time
 	UserAgent     string
+	// Fingerprint identifies the underlying failure (as opposed to this
+	// specific occurrence of it). See AnalyzerDispatcher.
+	Fingerprint string
 }
 
-// NewErrorHandler creates a new ErrorHandler
-func NewErrorHandler(templateFS fs.FS, analyzer ErrorAnalyzer) *ErrorHandler {
+// NewErrorHandler creates a new ErrorHandler.
+// A nil reporter falls back to a SlogReporter writing to os.Stderr.
+// A nil store disables error persistence and the /admin/errors endpoints.
+func NewErrorHandler(templateFS fs.FS, analyzer ErrorAnalyzer, reporter ErrorReporter, store ErrorStore) *ErrorHandler {
+	if reporter == nil {
+		reporter = NewSlogReporter(nil)
+	}
 	return &ErrorHandler{
 		templateFS: templateFS,
 		analyzer:   analyzer,
+		reporter:   reporter,
+		store:      store,
+	}
+}
+
+// generateTraceID creates a correlation ID for tying together every log line
+// and external report produced while handling a single error.
+func generateTraceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("TRACE-%d", time.Now().UnixNano()%1000000)
 	}
+	return hex.EncodeToString(b)
 }
 
 // loadErrorTemplate loads the error template once
@@ -84,34 +138,98 @@ func generateErrorID() string {
 	return fmt.Sprintf("OC-%s", hex.EncodeToString(b)[:12])
 }
 
-// HandleError handles an error by logging, triggering analysis, and rendering the error page
+// HandleError handles an error by reporting it, triggering analysis, and rendering the error page
 func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, statusCode int, err error, handlerFile, handlerFunc string) {
 	errorID := generateErrorID()
+	traceID := r.Header.Get("X-Trace-Id")
+	if traceID == "" {
+		traceID = generateTraceID()
+	}
 
 	var errorMsg string
 	if err != nil {
 		errorMsg = err.Error()
 	}
 
-	// Log the error
-	log.Printf("[%s] ERROR in %s.%s: %v (path: %s)", errorID, handlerFile, handlerFunc, err, r.URL.Path)
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+
+	// Report the error as a structured record so it can be correlated with
+	// the error ID shown on the pretty page, instead of a one-off log line.
+	// PanicRecoveryMiddleware captures the stack at the panic site and stashes
+	// it on the request context; for an error handled without a panic,
+	// HandleError captures its own stack instead.
+	stackTrace, _ := r.Context().Value(stackTraceContextKey{}).(string)
+	if stackTrace == "" {
+		stackTrace = string(debug.Stack())
+	}
+
+	record := ErrorRecord{
+		ErrorID:       errorID,
+		TraceID:       traceID,
+		RequestID:     requestid.FromContext(r.Context()),
+		HandlerFile:   handlerFile,
+		HandlerFunc:   handlerFunc,
+		RequestPath:   r.URL.Path,
+		RequestMethod: r.Method,
+		StatusCode:    statusCode,
+		ErrorMessage:  errorMsg,
+		StackTrace:    stackTrace,
+		UserAgent:     r.UserAgent(),
+		Timestamp:     time.Now(),
+	}
+	if reportErr := h.reporter.ReportError(r.Context(), record); reportErr != nil {
+		log.Printf("[%s] failed to report error: %v", errorID, reportErr)
+	}
+
+	// Fingerprint identifies the underlying failure so repeated incidents can
+	// be deduplicated (AnalyzerDispatcher) and counted even across restarts.
+	fingerprint := Fingerprint(handlerFile, handlerFunc, errorMsg, record.StackTrace)
+
+	// Persist the error before dispatching to the analyzer, so the error ID
+	// on the pretty page is recoverable even if analysis never completes.
+	if h.store != nil {
+		stored := &model.StoredError{
+			ErrorID:       errorID,
+			TraceID:       traceID,
+			Fingerprint:   fingerprint,
+			HandlerFile:   handlerFile,
+			HandlerFunc:   handlerFunc,
+			RequestPath:   r.URL.Path,
+			RequestMethod: r.Method,
+			StatusCode:    statusCode,
+			ErrorMessage:  errorMsg,
+			StackTrace:    record.StackTrace,
+			UserAgent:     r.UserAgent(),
+		}
+		if _, storeErr := h.store.CreateError(stored); storeErr != nil {
+			log.Printf("[%s] failed to persist error: %v", errorID, storeErr)
+		}
+	}
 
 	// Build error context for analysis
-	ctx := ErrorContext{
+	errCtx := ErrorContext{
 		ErrorID:       errorID,
+		TraceID:       traceID,
 		ErrorMessage:  errorMsg,
+		StackTrace:    record.StackTrace,
 		RequestPath:   r.URL.Path,
 		RequestMethod: r.Method,
 		HandlerFile:   handlerFile,
 		HandlerFunc:   handlerFunc,
-		Timestamp:     time.Now(),
+		StatusCode:    statusCode,
+		Timestamp:     record.Timestamp,
 		UserAgent:     r.UserAgent(),
+		Fingerprint:   fingerprint,
 	}
 
-	// Trigger async analysis if analyzer is configured
+	// Trigger async analysis if analyzer is configured. Deliberately not
+	// r.Context(): that's cancelled once this handler returns, well before
+	// a background analysis finishes.
 	if h.analyzer != nil {
 		go func() {
-			if analyzeErr := h.analyzer.AnalyzeError(ctx); analyzeErr != nil {
+			if analyzeErr := h.analyzer.AnalyzeError(context.Background(), errCtx); analyzeErr != nil {
 				log.Printf("[%s] Failed to trigger analysis: %v", errorID, analyzeErr)
 			}
 		}()
@@ -121,34 +239,55 @@ func (h *ErrorHandler) HandleError(w http.ResponseWriter, r *http.Request, statu
 	h.RenderErrorPage(w, errorID, errorMsg, statusCode)
 }
 
-// RenderErrorPage renders the beautiful error page
+// PanicRecoveryMiddleware recovers from panics raised anywhere in next,
+// capturing the stack trace at the panic site (debug.Stack() inside a
+// recover()'ing deferred function still sees the panicking frame, since the
+// stack isn't fully unwound until the deferred call returns) and routing the
+// failure through HandleError so a panic gets the same reporting,
+// persistence, and analysis dispatch as any other handled error.
+func (h *ErrorHandler) PanicRecoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				ctx := context.WithValue(r.Context(), stackTraceContextKey{}, string(debug.Stack()))
+				h.HandleError(w, r.WithContext(ctx), http.StatusInternalServerError, fmt.Errorf("panic: %v", rec), "unknown", "unknown")
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RenderErrorPage renders the beautiful error page, streaming the template
+// output directly to w behind a CSP scoped to a per-request nonce, instead of
+// buffering the whole page first. The tradeoff: once WriteHeader is called
+// below, a template execution error can only be logged, not recovered into a
+// clean error response, because the status code and CSP header are already
+// committed to the wire.
 func (h *ErrorHandler) RenderErrorPage(w http.ResponseWriter, errorID, errorMessage string, statusCode int) {
+	if statusCode == 0 {
+		statusCode = http.StatusInternalServerError
+	}
+
 	if err := h.loadErrorTemplate(); err != nil {
 		// Fallback to plain text if template fails
 		http.Error(w, fmt.Sprintf("Error ID: %s - OpenCode is working on a fix.", errorID), statusCode)
 		return
 	}
 
+	nonce := generateNonce()
 	data := ErrorPageData{
 		ErrorID:      errorID,
 		ErrorMessage: errorMessage,
 		ImageURL:     "", // Will use robot emoji by default
-	}
-
-	var buf bytes.Buffer
-	if err := errorTemplate.Execute(&buf, data); err != nil {
-		log.Printf("Failed to execute error template: %v", err)
-		http.Error(w, fmt.Sprintf("Error ID: %s - OpenCode is working on a fix.", errorID), statusCode)
-		return
-	}
-
-	if statusCode == 0 {
-		statusCode = http.StatusInternalServerError
+		Nonce:        nonce,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", cspHeader(nonce))
 	w.WriteHeader(statusCode)
-	_, _ = w.Write(buf.Bytes())
+	if err := errorTemplate.Execute(w, data); err != nil {
+		log.Printf("[%s] error template failed mid-stream: %v", errorID, err)
+	}
 }
 
 // RenderErrorPagePreview renders the error page for preview without triggering analysis
@@ -158,22 +297,37 @@ func (h *ErrorHandler) RenderErrorPagePreview(w http.ResponseWriter, errorID str
 		return
 	}
 
+	nonce := generateNonce()
 	data := ErrorPageData{
 		ErrorID:      errorID,
 		ErrorMessage: "", // No error message for preview
 		ImageURL:     "",
-	}
-
-	var buf bytes.Buffer
-	if err := errorTemplate.Execute(&buf, data); err != nil {
-		http.Error(w, "Failed to render error page", http.StatusInternalServerError)
-		return
+		Nonce:        nonce,
 	}
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Content-Security-Policy", cspHeader(nonce))
 	// Use 200 OK for preview (not 500)
 	w.WriteHeader(http.StatusOK)
-	_, _ = w.Write(buf.Bytes())
+	if err := errorTemplate.Execute(w, data); err != nil {
+		log.Printf("error template failed mid-stream: %v", err)
+	}
+}
+
+// generateNonce creates a per-request base64 value for the error page's CSP
+// header, so its inline assets can be allow-listed without 'unsafe-inline'.
+func generateNonce() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return hex.EncodeToString([]byte(fmt.Sprintf("fallback-%d", time.Now().UnixNano())))
+	}
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// cspHeader builds a strict CSP that allow-lists only same-origin assets plus
+// inline tags carrying the given nonce.
+func cspHeader(nonce string) string {
+	return fmt.Sprintf("default-src 'self'; script-src 'self' 'nonce-%s'; style-src 'self' 'nonce-%s'", nonce, nonce)
 }
 
 // HandleErrorPreview is the HTTP handler for /error route (preview mode)
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// fakeErrorStore is a minimal in-memory ErrorStore that just captures the
+// last StoredError passed to CreateError, so a test can inspect what
+// HandleError actually persisted.
+type fakeErrorStore struct {
+	stored *model.StoredError
+}
+
+func (s *fakeErrorStore) CreateError(e *model.StoredError) (int64, error) {
+	s.stored = e
+	return 1, nil
+}
+
+func (s *fakeErrorStore) GetErrorByID(errorID string) (*model.StoredError, error) {
+	return nil, nil
+}
+
+func (s *fakeErrorStore) ListErrors(limit, offset int) ([]*model.StoredError, error) {
+	return nil, nil
+}
+
+func (s *fakeErrorStore) UpdateErrorAnalysis(errorID, analysis string) error { return nil }
+
+func (s *fakeErrorStore) MarkResolved(errorID string) error { return nil }
+
+// nilDerefHandler panics with a nil pointer dereference, the class of bug
+// PanicRecoveryMiddleware exists to recover from while still persisting a
+// stack trace pointing back at this function.
+func nilDerefHandler(w http.ResponseWriter, r *http.Request) {
+	var fb *model.Feedback
+	_ = fb.ID // nil deref: fb is nil
+}
+
+// TestPanicRecoveryMiddlewarePersistsSymbolicatedStack induces a nil-deref
+// panic in a handler wrapped by PanicRecoveryMiddleware and asserts the
+// StoredError it persists carries a real, symbolicated stack trace - one
+// that names the panicking function and points at this file - rather than
+// the always-empty ErrorContext.StackTrace the middleware replaced.
+func TestPanicRecoveryMiddlewarePersistsSymbolicatedStack(t *testing.T) {
+	store := &fakeErrorStore{}
+	templateFS := fstest.MapFS{
+		"error.html": &fstest.MapFile{Data: []byte(`{{.ErrorID}}`)},
+	}
+	h := NewErrorHandler(templateFS, nil, NewSlogReporter(nil), store)
+
+	mw := h.PanicRecoveryMiddleware(http.HandlerFunc(nilDerefHandler))
+
+	req := httptest.NewRequest(http.MethodGet, "/boom", nil)
+	rec := httptest.NewRecorder()
+	mw.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("expected 500 after recovering the panic, got %d", rec.Code)
+	}
+
+	if store.stored == nil {
+		t.Fatal("expected the panic to persist a StoredError, got none")
+	}
+	if store.stored.StackTrace == "" {
+		t.Fatal("expected the persisted error to contain a stack trace, got empty string")
+	}
+	if !strings.Contains(store.stored.StackTrace, "nilDerefHandler") {
+		t.Errorf("expected stack trace to name the panicking function nilDerefHandler, got:\n%s", store.stored.StackTrace)
+	}
+	if !strings.Contains(store.stored.StackTrace, "error_handler_test.go") {
+		t.Errorf("expected stack trace to point at the offending file (error_handler_test.go), got:\n%s", store.stored.StackTrace)
+	}
+}
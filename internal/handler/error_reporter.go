@@ -0,0 +1,162 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// error_reporter.go gives HandleError a pluggable structured logging /
+// error-reporting pipeline, replacing the plain log.Printf calls with
+// machine-readable records that can be correlated with the error ID shown
+// on the pretty page.
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// ErrorReporter records a captured error so it can later be correlated with
+// the error ID shown on the pretty page. Implementations may persist the
+// record, forward it to an external dashboard, or both.
+type ErrorReporter interface {
+	// ReportError records the given error record. Implementations should
+	// avoid blocking the caller for long; slow sinks should dispatch
+	// asynchronously themselves.
+	ReportError(ctx context.Context, record ErrorRecord) error
+}
+
+// ErrorRecord is the structured, machine-readable representation of a
+// captured error. It mirrors ErrorContext (without Fingerprint) and adds
+// the originating RequestID, with JSON field names for log/report output.
+type ErrorRecord struct {
+	ErrorID       string    `json:"error_id"`
+	TraceID       string    `json:"trace_id"`
+	RequestID     string    `json:"request_id,omitempty"`
+	HandlerFile   string    `json:"handler_file"`
+	HandlerFunc   string    `json:"handler"`
+	RequestPath   string    `json:"path"`
+	RequestMethod string    `json:"method"`
+	StatusCode    int       `json:"status"`
+	ErrorMessage  string    `json:"error_message"`
+	StackTrace    string    `json:"stack_trace"`
+	UserAgent     string    `json:"user_agent,omitempty"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// SlogReporter is the default ErrorReporter. It writes each record as a
+// single structured JSON log line via log/slog, so operators can grep/filter
+// by error_id, trace_id, handler, path, etc. without parsing free-form text.
+type SlogReporter struct {
+	logger *slog.Logger
+}
+
+// NewSlogReporter creates a SlogReporter writing JSON lines to w.
+// A nil writer defaults to os.Stderr.
+func NewSlogReporter(w io.Writer) *SlogReporter {
+	if w == nil {
+		w = os.Stderr
+	}
+	return &SlogReporter{logger: slog.New(slog.NewJSONHandler(w, nil))}
+}
+
+// ReportError implements ErrorReporter.
+func (s *SlogReporter) ReportError(ctx context.Context, record ErrorRecord) error {
+	s.logger.LogAttrs(ctx, slog.LevelError, "handled error",
+		slog.String("error_id", record.ErrorID),
+		slog.String("trace_id", record.TraceID),
+		slog.String("request_id", record.RequestID),
+		slog.String("handler", record.HandlerFile+"."+record.HandlerFunc),
+		slog.String("path", record.RequestPath),
+		slog.String("method", record.RequestMethod),
+		slog.Int("status", record.StatusCode),
+		slog.String("error", record.ErrorMessage),
+		slog.String("stack_trace", record.StackTrace),
+		slog.Time("timestamp", record.Timestamp),
+	)
+	return nil
+}
+
+// WebhookErrorReporter forwards error records to an external error-tracking
+// endpoint (Sentry, GCP Error Reporting, or a generic webhook receiver).
+//
+// NOTE: delivers via a curl subprocess, unlike WebhookAnalyzer in
+// opencode_analyzer.go, which uses net/http.
+type WebhookErrorReporter struct {
+	webhookURL string
+	apiKey     string
+}
+
+// WebhookErrorReporterConfig holds configuration for WebhookErrorReporter.
+type WebhookErrorReporterConfig struct {
+	WebhookURL string
+	APIKey     string
+}
+
+// NewWebhookErrorReporter creates a WebhookErrorReporter. Falls back to the
+// ERROR_REPORTING_WEBHOOK_URL / ERROR_REPORTING_API_KEY environment
+// variables when the config fields are empty.
+func NewWebhookErrorReporter(config WebhookErrorReporterConfig) *WebhookErrorReporter {
+	if config.WebhookURL == "" {
+		config.WebhookURL = os.Getenv("ERROR_REPORTING_WEBHOOK_URL")
+	}
+	if config.APIKey == "" {
+		config.APIKey = os.Getenv("ERROR_REPORTING_API_KEY")
+	}
+	return &WebhookErrorReporter{webhookURL: config.WebhookURL, apiKey: config.APIKey}
+}
+
+// ReportError implements ErrorReporter. Forwarding happens asynchronously
+// since it's a background best-effort sink and must not delay the caller.
+func (w *WebhookErrorReporter) ReportError(ctx context.Context, record ErrorRecord) error {
+	if w.webhookURL == "" {
+		return nil
+	}
+	go w.send(record)
+	return nil
+}
+
+func (w *WebhookErrorReporter) send(record ErrorRecord) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	payload := fmt.Sprintf(`{"error_id":%q,"trace_id":%q,"handler":%q,"path":%q,"method":%q,"status":%d,"error":%q}`,
+		record.ErrorID, record.TraceID, record.HandlerFile+"."+record.HandlerFunc,
+		record.RequestPath, record.RequestMethod, record.StatusCode, record.ErrorMessage)
+
+	args := []string{"-s", "-X", "POST", "-H", "Content-Type: application/json"}
+	if w.apiKey != "" {
+		args = append(args, "-H", fmt.Sprintf("X-API-Key: %s", w.apiKey))
+	}
+	args = append(args, "-d", payload, w.webhookURL)
+
+	cmd := exec.CommandContext(ctx, "curl", args...)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		log.Printf("[%s] error report webhook failed: %v\nOutput: %s", record.ErrorID, err, string(output))
+	}
+}
+
+// CompositeReporter fans a single error record out to multiple reporters
+// (e.g. local structured logs plus an external dashboard). Mirrors
+// CompositeAnalyzer in opencode_analyzer.go.
+type CompositeReporter struct {
+	reporters []ErrorReporter
+}
+
+// NewCompositeReporter creates a CompositeReporter.
+func NewCompositeReporter(reporters ...ErrorReporter) *CompositeReporter {
+	return &CompositeReporter{reporters: reporters}
+}
+
+// ReportError implements ErrorReporter, reporting to every configured sink.
+func (c *CompositeReporter) ReportError(ctx context.Context, record ErrorRecord) error {
+	var lastErr error
+	for _, reporter := range c.reporters {
+		if err := reporter.ReportError(ctx, record); err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
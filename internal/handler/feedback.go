@@ -11,34 +11,66 @@
 package handler
 
 import (
-	"context"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log"
+	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/microcosm-cc/bluemonday"
+	"golang.org/x/time/rate"
+
+	"github.com/bluefermion/feedback/internal/metrics"
 	"github.com/bluefermion/feedback/internal/model"
 	"github.com/bluefermion/feedback/internal/repository"
+	"github.com/bluefermion/feedback/internal/requestid"
 	"github.com/bluefermion/feedback/internal/selfhealing"
 )
 
+// searchRateLimit and searchRateBurst bound HandleList's full-text-search
+// path (?q=) per client IP - generous enough for a real user paging through
+// search results, tight enough to blunt an anonymous scraper.
+const (
+	searchRateLimit = rate.Limit(1) // 1 search/sec sustained
+	searchRateBurst = 5
+)
+
 // FeedbackHandler groups all methods related to feedback operations.
 // It holds references to its dependencies (repository, self-healing service, templates).
 type FeedbackHandler struct {
-	repo     *repository.SQLiteRepository
-	selfheal *selfhealing.Trigger
-	// templates maps a page name (e.g., "demo") to its fully parsed template instance.
-	// This approach (Pre-computation/Caching) is thread-safe and performant.
-	templates map[string]*template.Template
+	repo      *repository.SQLiteRepository
+	selfheal  *selfhealing.Trigger
+	collector metrics.Collector
+	// renderer executes page templates (see Renderer). Cached/parse-once in
+	// production; swapped for a DevRenderer that re-parses from disk on
+	// every request when FEEDBACK_DEV=1.
+	renderer Renderer
+	// markdownPolicy sanitizes the HTML safeMarkdown renders from LLM
+	// analysis text (see markdown.go). Exposed as a field, rather than a
+	// package-level default, so callers can swap in a stricter policy.
+	markdownPolicy *bluemonday.Policy
+	// searchLimiter rate-limits HandleList's full-text-search path per
+	// client IP (see rate_limiter.go).
+	searchLimiter *ipRateLimiter
+	// logger emits structured (slog) audit events for feedback submission
+	// and self-healing dispatch, carrying the request ID so they can be
+	// correlated with the access log line newStructuredLoggingMiddleware
+	// writes for the same request.
+	logger *slog.Logger
 }
 
 // NewFeedbackHandler is the constructor for FeedbackHandler.
-// It performs dependency injection and initialization logic.
-func NewFeedbackHandler(repo *repository.SQLiteRepository, templateFS fs.FS) *FeedbackHandler {
+// It performs dependency injection and initialization logic. collector may
+// be nil, in which case self-healing metrics are silently discarded (see
+// metrics.NewNoop). logger may be nil, in which case slog.Default() is used.
+func NewFeedbackHandler(repo *repository.SQLiteRepository, templateFS fs.FS, collector metrics.Collector, logger *slog.Logger) *FeedbackHandler {
 	// Initialize self-healing triggers based on environment configuration.
 	// This demonstrates "feature flagging" via configuration.
 	config := selfhealing.DefaultConfig()
@@ -48,7 +80,7 @@ func NewFeedbackHandler(repo *repository.SQLiteRepository, templateFS fs.FS) *Fe
 		if err := config.Validate(); err != nil {
 			log.Printf("Self-healing config invalid, disabling: %v", err)
 		} else {
-			trigger = selfhealing.NewTrigger(config)
+			trigger = selfhealing.NewTrigger(config, repo, collector)
 			if config.Mode == "opencode" {
 				log.Printf("Self-healing enabled (opencode mode, admins only: %v)", config.AdminEmails)
 			} else {
@@ -57,71 +89,60 @@ func NewFeedbackHandler(repo *repository.SQLiteRepository, templateFS fs.FS) *Fe
 		}
 	}
 
-	// Initialize templates with custom functions
+	if collector == nil {
+		collector = metrics.NewNoop()
+	}
+
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	h := &FeedbackHandler{
+		repo:           repo,
+		selfheal:       trigger,
+		collector:      collector,
+		markdownPolicy: newMarkdownPolicy(),
+		searchLimiter:  newIPRateLimiter(searchRateLimit, searchRateBurst),
+		logger:         logger,
+	}
+
+	// Custom template functions. safeMarkdown is a method (see markdown.go)
+	// since it needs h.markdownPolicy; safeURL doesn't need any state.
 	funcMap := template.FuncMap{
 		"safeURL": func(s string) template.URL {
 			// Mark data URLs as safe for img src attributes
 			return template.URL(s)
 		},
-		"safeMarkdown": func(s string) template.HTML {
-			// A crude Markdown-to-HTML parser.
-			// In production, use "github.com/yuin/goldmark" or similar libraries.
-			// We are manually replacing syntax to avoid pulling in heavy dependencies for this demo.
-			content := template.HTMLEscapeString(s)
-			content = strings.ReplaceAll(content, "\n## ", "\n</p><h3>")
-			content = strings.ReplaceAll(content, "\n\n", "</p><p>")
-			content = strings.ReplaceAll(content, "```", "</code></pre><pre><code>")
-
-			// Fix invalid initial tag nesting
-			if strings.HasPrefix(content, "</p><h3>") {
-				content = "<h3>" + content[8:]
-			}
-			return template.HTML(content)
-		},
+		"safeMarkdown": h.safeMarkdown,
 	}
 
-	// TEMPLATE CACHING STRATEGY:
-	// We use the "Base + Page" inheritance pattern.
-	// 1. Parse the shared "base.html" (layout) once.
-	// 2. For each page, CLONE the base and parse the specific page file into it.
-	// 3. Store the result in a map keyed by the page name.
-
-	// Step 1: Parse Base
-	// template.Must() is a helper that panics if the error is non-nil.
-	// This is idiomatic for initialization code: if templates are broken, the app MUST crash on startup.
-	baseTmpl := template.Must(template.New("base.html").Funcs(funcMap).ParseFS(templateFS, "base.html"))
-
-	templates := make(map[string]*template.Template)
+	// Pages this handler renders. Both NewRenderer (production, parse-once)
+	// and NewDevRenderer (FEEDBACK_DEV=1, parse-per-request) clone
+	// "base.html" per page in this list - see Renderer.
 	pages := []string{"demo.html", "feedback_list.html", "feedback_detail.html"}
 
-	// Step 2: Clone and Parse Pages
-	for _, page := range pages {
-		// Clone() ensures we start fresh with the base layout for each page.
-		// template.Must() handles the error checking for both Clone and ParseFS.
-		// If either fails, the application panics immediately (Fail Fast).
-
-		// 1. Clone the base (cheap copy)
-		clone := template.Must(baseTmpl.Clone())
-
-		// 2. Parse the specific page content into the clone
-		// This overwrites the {{template "content"}} definition for this specific instance.
-		parsed := template.Must(clone.ParseFS(templateFS, page))
-
-		templates[page] = parsed
+	if os.Getenv("FEEDBACK_DEV") == "1" {
+		h.renderer = NewDevRenderer(templateFS, funcMap, pages)
+		log.Printf("Template hot-reload enabled (FEEDBACK_DEV=1): re-parsing templates from disk on every request")
+	} else {
+		h.renderer = NewRenderer(templateFS, funcMap, pages)
 	}
 
-	return &FeedbackHandler{
-		repo:      repo,
-		selfheal:  trigger,
-		templates: templates,
-	}
+	return h
 }
 
 // render renders a template by name with the given data.
-// It supports HTMX by detecting the "HX-Request" header and rendering only the content block.
+//
+// It supports HTMX by detecting the "HX-Request" header and rendering only
+// a block instead of the full page: the block named by the "HX-Target-Block"
+// header if present, otherwise "content" (the whole page body), so a
+// response can target a specific fragment - e.g. out-of-band swapping a
+// single feedback row or detail panel - without a full-page reload.
 func (h *FeedbackHandler) render(w http.ResponseWriter, r *http.Request, name string, data interface{}) {
-	tmpl, ok := h.templates[name]
-	if !ok {
+	start := time.Now()
+	defer func() { h.collector.ObserveRenderDuration(name, time.Since(start)) }()
+
+	if !h.renderer.Have(name) {
 		// This should only happen if we forgot to add a page to the 'pages' list above.
 		http.Error(w, fmt.Sprintf("Template %s not found", name), http.StatusInternalServerError)
 		return
@@ -129,19 +150,21 @@ func (h *FeedbackHandler) render(w http.ResponseWriter, r *http.Request, name st
 
 	w.Header().Set("Content-Type", "text/html")
 
-	// HTMX LOGIC:
-	// If this is an HTMX request, the client only wants the HTML fragment for the body,
-	// not the full <html><head>... wrapper.
 	isHTMX := r.Header.Get("HX-Request") == "true"
-
-	target := "base.html"
-	if isHTMX {
-		target = "content"
+	if !isHTMX {
+		if err := h.renderer.Render(w, r, name, data); err != nil {
+			log.Printf("Template error rendering %s: %v", name, err)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		}
+		return
 	}
 
-	// Execute the chosen target.
-	if err := tmpl.ExecuteTemplate(w, target, data); err != nil {
-		log.Printf("Template error rendering %s (target=%s): %v", name, target, err)
+	block := r.Header.Get("HX-Target-Block")
+	if block == "" {
+		block = "content"
+	}
+	if err := h.renderer.RenderFragment(w, r, name, block, data); err != nil {
+		log.Printf("Template error rendering %s (block=%s): %v", name, block, err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
@@ -153,10 +176,74 @@ func (h *FeedbackHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 	// 1. INPUT PARSING
 	// -------------------------------------------------------------------------
 
+	// We read the whole body up front, rather than streaming straight into
+	// json.Decoder, because the Idempotency-Key check below needs to hash
+	// it before it's parsed.
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "Failed to read request body", err.Error())
+		return
+	}
+
+	// -------------------------------------------------------------------------
+	// 1b. IDEMPOTENCY (Idempotency-Key)
+	// -------------------------------------------------------------------------
+
+	// A client-supplied Idempotency-Key lets a retried POST (flaky mobile
+	// networks love to double-send after a timeout) return the original
+	// response instead of creating a second feedback row and paying for a
+	// second self-healing run. See SQLiteRepository.ReserveIdempotencyKey.
+	if idempotencyKey := r.Header.Get("Idempotency-Key"); idempotencyKey != "" {
+		bodyHash := hashIdempotencyBody(body)
+		cached, conflict, err := h.repo.ReserveIdempotencyKey(r.Context(), idempotencyKey, bodyHash)
+		if err != nil {
+			h.writeError(w, http.StatusInternalServerError, "Failed to process request", err.Error())
+			return
+		}
+		if conflict {
+			h.writeError(w, http.StatusConflict,
+				"Idempotency-Key was already used with a different request body", "")
+			return
+		}
+		if cached != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(cached.ResponseStatus)
+			w.Write([]byte(cached.ResponseBody))
+			return
+		}
+
+		// We reserved the key: we're the one doing the real work below, and
+		// must persist whatever response we end up writing so a retry of
+		// this same key can replay it.
+		rec := &idempotencyRecorder{ResponseWriter: w}
+		defer func() {
+			status, body := rec.status, rec.body
+			if status == 0 {
+				// This defer still runs if the handler panics below (defers
+				// fire during panic unwind, before PanicRecoveryMiddleware's
+				// recover() further up the chain sees it, and that
+				// middleware writes its 500 page to the original w, never
+				// through rec). Persisting status 0 here would look
+				// identical to "still in flight" to
+				// ReserveIdempotencyKey/readIdempotencyKey, wedging every
+				// retry of this key into a 5s poll timeout for up to
+				// IdempotencyKeyTTL instead of being reprocessed.
+				status = http.StatusInternalServerError
+				body = []byte(`{"error":"internal server error"}`)
+			}
+			if err := h.repo.SaveIdempotencyResponse(r.Context(), idempotencyKey, status, string(body)); err != nil {
+				h.logger.ErrorContext(r.Context(), "failed to save idempotency response",
+					"request_id", requestid.FromContext(r.Context()),
+					"error", err)
+			}
+		}()
+		w = rec
+	}
+
 	// We use json.Decoder to stream-parse the request body directly into our struct.
 	// This is memory efficient and robust.
 	var req model.FeedbackRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	if err := json.Unmarshal(body, &req); err != nil {
 		h.writeError(w, http.StatusBadRequest, "Invalid JSON", err.Error())
 		return
 	}
@@ -172,10 +259,12 @@ func (h *FeedbackHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 	// Enforce required fields.
 	// Returning explicit 400 Bad Request errors helps frontend developers debug.
 	if req.Title == "" {
+		h.collector.ObserveFeedbackSubmission(req.Type, "rejected")
 		h.writeError(w, http.StatusBadRequest, "Title is required", "")
 		return
 	}
 	if req.Description == "" {
+		h.collector.ObserveFeedbackSubmission(req.Type, "rejected")
 		h.writeError(w, http.StatusBadRequest, "Description is required", "")
 		return
 	}
@@ -229,7 +318,11 @@ func (h *FeedbackHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 		guardResult := guards.CheckPromptInjection(r.Context(), userInput)
 
 		if guardResult.Blocked {
-			log.Printf("Feedback blocked by guard: %s", guardResult.Reason)
+			h.logger.WarnContext(r.Context(), "feedback blocked by guard",
+				"request_id", requestid.FromContext(r.Context()),
+				"reason", guardResult.Reason)
+			h.collector.ObserveFeedbackSubmission(req.Type, "rejected")
+			h.collector.ObserveGuardBlock(guardResult.Reason)
 			h.writeError(w, http.StatusBadRequest,
 				"Your feedback couldn't be processed. Please rephrase and try again.",
 				"")
@@ -243,13 +336,21 @@ func (h *FeedbackHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 
 	id, err := h.repo.Create(feedback)
 	if err != nil {
-		log.Printf("Failed to create feedback: %v", err)
+		h.logger.ErrorContext(r.Context(), "failed to create feedback",
+			"request_id", requestid.FromContext(r.Context()),
+			"error", err)
+		h.collector.ObserveFeedbackSubmission(req.Type, "error")
 		h.writeError(w, http.StatusInternalServerError, "Failed to save feedback", "")
 		return
 	}
 
 	feedback.ID = id
-	log.Printf("Created feedback #%d: %s", id, feedback.Title)
+	h.collector.ObserveFeedbackSubmission(req.Type, "created")
+	h.logger.InfoContext(r.Context(), "created feedback",
+		"request_id", requestid.FromContext(r.Context()),
+		"feedback_id", id,
+		"user_email", feedback.UserEmail,
+		"title", feedback.Title)
 
 	// -------------------------------------------------------------------------
 	// 6. ASYNC BACKGROUND PROCESSING (Self-Healing)
@@ -258,38 +359,47 @@ func (h *FeedbackHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 	// We check if this feedback qualifies for auto-analysis.
 	selfHealingTriggered := false
 	if h.selfheal != nil {
-		if canTrigger, reason := h.selfheal.CanTrigger(feedback); canTrigger {
-			log.Printf("Triggering self-healing for feedback #%d", id)
+		// The prompt-injection guard above already ran (synchronously,
+		// before this feedback had an ID); publish it now that HandleSelfHealingStream
+		// has a feedback ID to key the event on.
+		h.selfheal.Hub().Publish(id, selfhealing.StageGuardCheck, "guard check passed")
 
-			// CRITICAL: We launch this in a goroutine (go func() {...}).
-			// We MUST NOT block the HTTP response while waiting for the LLM (which takes seconds).
-			// The user gets an immediate "Success" response, while the analysis happens in background.
+		if canTrigger, reason := h.selfheal.CanTrigger(feedback); canTrigger {
+			requestID := requestid.FromContext(r.Context())
+			h.logger.InfoContext(r.Context(), "triggering self-healing",
+				"request_id", requestID,
+				"feedback_id", id)
+
+			// TriggerAsync just enqueues a durable job row and returns
+			// immediately; the actual analysis runs later on Trigger's own
+			// worker pool (which also persists the analysis once it
+			// finishes), so this goroutine only needs to wait on the
+			// returned channel long enough to log the eventual outcome. It
+			// outlives the request, so it logs with the captured requestID
+			// rather than r.Context(), which is canceled once the handler
+			// returns.
+			resultCh := h.selfheal.TriggerAsync(r.Context(), feedback)
 			go func() {
-				// Create a background context because the request context (r.Context())
-				// will be cancelled as soon as HandleSubmit returns.
-				resultCh := h.selfheal.TriggerAsync(context.Background(), feedback)
 				result := <-resultCh
-
 				if result.Success {
-					log.Printf("Self-healing completed for feedback #%d: %s", id, result.Message)
-					// If analysis succeeded, update the record in the database.
-					if result.Output != "" {
-						if err := h.repo.UpdateAnalysis(id, result.Output); err != nil {
-							log.Printf("Failed to store analysis for feedback #%d: %v", id, err)
-						} else {
-							log.Printf("Analysis stored for feedback #%d", id)
-						}
-					}
-					if result.PRURL != "" {
-						log.Printf("PR created: %s", result.PRURL)
-					}
+					h.logger.Info("self-healing completed",
+						"request_id", requestID,
+						"feedback_id", id,
+						"message", result.Message,
+						"pr_url", result.PRURL)
 				} else {
-					log.Printf("Self-healing failed for feedback #%d: %s", id, result.Error)
+					h.logger.Error("self-healing failed",
+						"request_id", requestID,
+						"feedback_id", id,
+						"error", result.Error)
 				}
 			}()
 			selfHealingTriggered = true
 		} else {
-			log.Printf("Self-healing not triggered for feedback #%d: %s", id, reason)
+			h.logger.InfoContext(r.Context(), "self-healing not triggered",
+				"request_id", requestid.FromContext(r.Context()),
+				"feedback_id", id,
+				"reason", reason)
 		}
 	}
 
@@ -311,28 +421,32 @@ func (h *FeedbackHandler) HandleSubmit(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
-// HandleList processes GET /api/feedback requests (Pagination).
+// HandleList processes GET /api/feedback requests. Pagination is
+// cursor-based (see repository.ListOptions) rather than OFFSET, and accepts
+// the same query params as HandleFeedbackList (see parseListOptions). The
+// full-text-search path (?q=) is additionally rate-limited per client IP -
+// it's the one expensive query the FTS5 virtual table has to run, so it's
+// the one anonymous scrapers could use to degrade the whole service.
 func (h *FeedbackHandler) HandleList(w http.ResponseWriter, r *http.Request) {
-	// Defaults
-	limit := 50
-	offset := 0
-
-	// Parse query parameters safely.
-	// Always validate and bound user input to prevent DOS attacks (e.g., limit=1000000).
-	if l := r.URL.Query().Get("limit"); l != "" {
-		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
-			limit = parsed
-		}
+	opts := parseListOptions(r)
+
+	if opts.Query != "" && !h.searchLimiter.allow(clientIP(r)) {
+		h.writeError(w, http.StatusTooManyRequests, "Too many search requests, please slow down", "")
+		return
 	}
-	if o := r.URL.Query().Get("offset"); o != "" {
-		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
-			offset = parsed
-		}
+
+	filterLabel := "browse"
+	if opts.Query != "" {
+		filterLabel = "search"
 	}
 
-	feedbacks, err := h.repo.List(limit, offset)
+	start := time.Now()
+	feedbacks, nextCursor, prevCursor, err := h.repo.ListFiltered(opts)
+	h.collector.ObserveListQueryDuration(filterLabel, time.Since(start))
 	if err != nil {
-		log.Printf("Failed to list feedback: %v", err)
+		h.logger.ErrorContext(r.Context(), "failed to list feedback",
+			"request_id", requestid.FromContext(r.Context()),
+			"error", err)
 		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve feedback", "")
 		return
 	}
@@ -348,7 +462,11 @@ func (h *FeedbackHandler) HandleList(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(feedbacks)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"items":      feedbacks,
+		"nextCursor": nextCursor,
+		"prevCursor": prevCursor,
+	})
 }
 
 // HandleGet processes GET /api/feedback/{id}.
@@ -392,6 +510,13 @@ func (h *FeedbackHandler) HandleSelfHealingStatus(w http.ResponseWriter, r *http
 	json.NewEncoder(w).Encode(h.selfheal.Status())
 }
 
+// SelfHealingTrigger exposes the self-healing worker so cmd/server can drain
+// it on graceful shutdown (Trigger.Wait) and report its running state on
+// /health, without exposing the field itself.
+func (h *FeedbackHandler) SelfHealingTrigger() *selfhealing.Trigger {
+	return h.selfheal
+}
+
 // extractMetadata Helper: Safely extracts strongly-typed values from the untyped JSON map.
 // The frontend might send numbers as float64 (JSON standard), so we cast them to int.
 func (h *FeedbackHandler) extractMetadata(feedback *model.Feedback, metadata map[string]interface{}) {
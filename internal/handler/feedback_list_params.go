@@ -0,0 +1,48 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// feedback_list_params.go is shared between HandleFeedbackList and
+// HandleList: both page through repository.ListFiltered using the same
+// query-param vocabulary (limit, cursor, direction, and the triage
+// filters), so a bookmarked/linked HTML page and the API client see
+// identical pagination behavior.
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/repository"
+)
+
+// parseListOptions builds a repository.ListOptions from URL query params.
+func parseListOptions(r *http.Request) repository.ListOptions {
+	opts := repository.ListOptions{
+		Cursor:            r.URL.Query().Get("cursor"),
+		Direction:         r.URL.Query().Get("direction"),
+		Status:            r.URL.Query().Get("status"),
+		Priority:          r.URL.Query().Get("priority"),
+		Category:          r.URL.Query().Get("category"),
+		Type:              r.URL.Query().Get("type"),
+		PredictedPriority: r.URL.Query().Get("predicted_priority"),
+		Query:             r.URL.Query().Get("q"),
+	}
+
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			opts.Limit = parsed
+		}
+	}
+	if s := r.URL.Query().Get("since"); s != "" {
+		if parsed, err := time.Parse(time.RFC3339, s); err == nil {
+			opts.Since = parsed
+		}
+	}
+	if h := r.URL.Query().Get("has_analysis"); h != "" {
+		if parsed, err := strconv.ParseBool(h); err == nil {
+			opts.HasAnalysis = &parsed
+		}
+	}
+
+	return opts
+}
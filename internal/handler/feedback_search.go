@@ -0,0 +1,84 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// feedback_search.go exposes full-text search over feedback via
+// repository.SQLiteRepository.Search (SQLite FTS5).
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/repository"
+)
+
+// HandleSearch processes GET /api/feedback/search?q=...
+func (h *FeedbackHandler) HandleSearch(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		h.writeError(w, http.StatusBadRequest, "Query parameter 'q' is required", "")
+		return
+	}
+
+	// This is the dedicated FTS5 search path - rate-limit it the same way
+	// HandleList rate-limits its own ?q= path, or an anonymous client can
+	// just hit this route instead to bypass that limiter entirely.
+	if !h.searchLimiter.allow(clientIP(r)) {
+		h.writeError(w, http.StatusTooManyRequests, "Too many search requests, please slow down", "")
+		return
+	}
+
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	filters := repository.SearchFilters{
+		Status:            r.URL.Query().Get("status"),
+		Type:              r.URL.Query().Get("type"),
+		Priority:          r.URL.Query().Get("priority"),
+		Category:          r.URL.Query().Get("category"),
+		PredictedPriority: r.URL.Query().Get("predicted_priority"),
+	}
+	if v := r.URL.Query().Get("is_mobile"); v != "" {
+		if parsed, err := strconv.ParseBool(v); err == nil {
+			filters.IsMobile = &parsed
+		}
+	}
+	if v := r.URL.Query().Get("created_after"); v != "" {
+		if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+			filters.CreatedAfter = parsed
+		}
+	}
+	if v := r.URL.Query().Get("created_before"); v != "" {
+		if parsed, err := time.Parse(time.RFC3339, v); err == nil {
+			filters.CreatedBefore = parsed
+		}
+	}
+
+	results, total, err := h.repo.Search(query, filters, limit, offset)
+	if err != nil {
+		log.Printf("Search failed: %v", err)
+		h.writeError(w, http.StatusInternalServerError, "Search failed", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"results": results,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
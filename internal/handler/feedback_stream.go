@@ -0,0 +1,104 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// feedback_stream.go exposes self-healing job progress over Server-Sent
+// Events, backed by the selfhealing.JobHub the same Trigger already
+// publishes to from TriggerAsync/runJob/finish.
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/selfhealing"
+)
+
+// sseHeartbeatInterval matches long-running worker servers like the vulndb
+// worker: frequent enough that proxies/load balancers don't time out an
+// idle connection, infrequent enough not to spam the client.
+const sseHeartbeatInterval = 15 * time.Second
+
+// HandleSelfHealingStream upgrades GET /api/feedback/{id}/stream to a
+// Server-Sent Events connection and pushes the feedback's self-healing job
+// progress (queued, guard-check, analyzing, patch-generated, pr-opened,
+// done/failed, plus log fragments) as selfhealing.Trigger's worker pool
+// publishes them. Multiple clients can attach to the same feedback ID, and
+// a reconnecting client can resume via the Last-Event-ID header - JobHub
+// replays its buffered history before streaming live events.
+func (h *FeedbackHandler) HandleSelfHealingStream(w http.ResponseWriter, r *http.Request) {
+	if h.selfheal == nil {
+		h.writeError(w, http.StatusServiceUnavailable, "Self-healing is not enabled", "")
+		return
+	}
+
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid feedback ID", "")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		h.writeError(w, http.StatusInternalServerError, "Streaming unsupported", "")
+		return
+	}
+
+	var lastEventID int64
+	if v := r.Header.Get("Last-Event-ID"); v != "" {
+		if parsed, err := strconv.ParseInt(v, 10, 64); err == nil {
+			lastEventID = parsed
+		}
+	}
+
+	replay, live, unsubscribe := h.selfheal.Hub().Subscribe(id, lastEventID)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		writeSSEEvent(w, event)
+		if event.Stage == selfhealing.StageDone || event.Stage == selfhealing.StageFailed {
+			flusher.Flush()
+			return
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event := <-live:
+			writeSSEEvent(w, event)
+			flusher.Flush()
+			if event.Stage == selfhealing.StageDone || event.Stage == selfhealing.StageFailed {
+				return
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSEEvent writes event in SSE wire format: an id: line, an event:
+// line naming its stage, one data: line per line of Message (per the SSE
+// spec, a multi-line data field is sent as repeated data: lines), and the
+// blank line that terminates the event.
+func writeSSEEvent(w http.ResponseWriter, event selfhealing.StreamEvent) {
+	fmt.Fprintf(w, "id: %d\n", event.ID)
+	fmt.Fprintf(w, "event: %s\n", event.Stage)
+	for _, line := range strings.Split(event.Message, "\n") {
+		fmt.Fprintf(w, "data: %s\n", line)
+	}
+	fmt.Fprint(w, "\n")
+}
@@ -0,0 +1,44 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// idempotency.go backs HandleSubmit's Idempotency-Key support: flaky
+// mobile networks retry POSTs, which would otherwise create duplicate
+// feedback rows and duplicate (LLM-token-costing) self-healing runs.
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+)
+
+// hashIdempotencyBody fingerprints a request body so a replayed
+// Idempotency-Key can be checked against the body it was originally paired
+// with: reusing a key with a different body is a client bug, not a retry,
+// and gets a 409 instead of silently returning the old response.
+func hashIdempotencyBody(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// idempotencyRecorder wraps a ResponseWriter to capture the status and
+// body HandleSubmit ends up writing, so it can be persisted via
+// SQLiteRepository.SaveIdempotencyResponse and replayed verbatim on a
+// future retry of the same Idempotency-Key.
+type idempotencyRecorder struct {
+	http.ResponseWriter
+	status int
+	body   []byte
+}
+
+func (rec *idempotencyRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *idempotencyRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+	rec.body = append(rec.body, b...)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,69 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// markdown.go renders LLM-authored analysis text as sanitized HTML for the
+// "safeMarkdown" template func. It replaces an earlier string-replace hack
+// that mangled anything beyond the simplest input (nested fences, lists,
+// links, tables) with a real CommonMark parser piped through an HTML
+// sanitizer, since this content originates from an LLM and must be treated
+// as untrusted.
+package handler
+
+import (
+	"bytes"
+	"html/template"
+	"log"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// markdownRenderer parses CommonMark plus the GFM extensions (tables,
+// strikethrough, task lists, autolinks) analysis text commonly uses.
+// Goldmark runs in its default (non-unsafe) mode, which already omits raw
+// HTML embedded in the source instead of passing it through; bluemonday's
+// sanitization pass is still the guard this package relies on against
+// adversarial LLM output.
+var markdownRenderer = goldmark.New(goldmark.WithExtensions(extension.GFM))
+
+// newMarkdownPolicy builds the bluemonday policy safeMarkdown sanitizes
+// rendered analysis HTML with. It's deliberately narrower than
+// bluemonday.UGCPolicy(): headings start at h2 (h1 is reserved for the page
+// layout), images are never allowed (analysis text has no business
+// embedding remote or data: URI images), and every link gets rel=nofollow.
+func newMarkdownPolicy() *bluemonday.Policy {
+	p := bluemonday.NewPolicy()
+
+	p.AllowElements("p", "br", "hr")
+	p.AllowElements("h2", "h3", "h4", "h5", "h6")
+	p.AllowElements("strong", "em", "del", "blockquote")
+	p.AllowElements("ul", "ol", "li")
+	p.AllowElements("pre", "code")
+	p.AllowAttrs("class").Matching(bluemonday.SpaceSeparatedTokens).OnElements("code")
+
+	// GFM tables.
+	p.AllowElements("table", "thead", "tbody", "tr", "th", "td")
+
+	// GFM task lists render as disabled checkboxes, checked when ticked.
+	p.AllowAttrs("type", "checked", "disabled").OnElements("input")
+
+	// Links, but no images.
+	p.AllowAttrs("href").OnElements("a")
+	p.AllowStandardURLs()
+	p.RequireNoFollowOnLinks(true)
+
+	return p
+}
+
+// safeMarkdown is the "safeMarkdown" template func: renders s as CommonMark
+// and sanitizes the result through h.markdownPolicy. Falls back to
+// escaping s verbatim if goldmark can't parse it, rather than risking
+// unsanitized output reaching the response.
+func (h *FeedbackHandler) safeMarkdown(s string) template.HTML {
+	var buf bytes.Buffer
+	if err := markdownRenderer.Convert([]byte(s), &buf); err != nil {
+		log.Printf("Failed to render markdown: %v", err)
+		return template.HTML(template.HTMLEscapeString(s))
+	}
+	return template.HTML(h.markdownPolicy.SanitizeBytes(buf.Bytes()))
+}
@@ -5,34 +5,79 @@
 package handler
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
+	"net/http"
 	"os"
-	"os/exec"
+	"strconv"
 	"time"
+
+	"github.com/bluefermion/feedback/internal/metrics"
+	"github.com/bluefermion/feedback/internal/model"
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
 )
 
-// OpenCodeAnalyzer implements ErrorAnalyzer using the OpenCode Docker container
+// OpenCodeAnalyzer implements ErrorAnalyzer using the OpenCode Docker
+// container, talking to the Docker Engine API directly (github.com/docker/
+// docker/client) rather than shelling out to the docker CLI: this gets us
+// structured errors instead of an opaque "exit status 1", line-by-line
+// stdout/stderr streaming instead of buffering the whole analysis, and
+// doesn't require the docker binary on PATH.
 type OpenCodeAnalyzer struct {
 	containerName string
 	enabled       bool
+	autoStart     bool
+	image         string
+	workspaceDir  string
+	docker        *client.Client
+	collector     metrics.Collector
 }
 
 // OpenCodeConfig holds configuration for the OpenCode analyzer
 type OpenCodeConfig struct {
 	ContainerName string
 	Enabled       bool
+	// Image is the image ContainerAutoStart pulls and creates ContainerName
+	// from when it isn't already running. Defaults to ContainerName.
+	Image string
+	// ContainerAutoStart pulls Image and creates/starts ContainerName when
+	// AnalyzeError finds it isn't running, instead of just failing.
+	ContainerAutoStart bool
+	// WorkspaceDir, if set, is bind-mounted into the container at
+	// /workspace (read-only) so the agent can inspect source when
+	// auto-starting it.
+	WorkspaceDir string
+	// Collector records feedback_analyzer_duration_seconds and
+	// feedback_analyzer_inflight for this analyzer. Defaults to
+	// metrics.NewNoop() if nil.
+	Collector metrics.Collector
 }
 
-// NewOpenCodeAnalyzer creates a new OpenCodeAnalyzer
+// NewOpenCodeAnalyzer creates a new OpenCodeAnalyzer. The Docker client is
+// built once here (honoring DOCKER_HOST/DOCKER_TLS_VERIFY/DOCKER_CERT_PATH
+// via client.FromEnv) rather than per-call, matching how every other client
+// in this codebase (LLM providers, the repository) is constructed once and
+// reused.
 func NewOpenCodeAnalyzer(config OpenCodeConfig) *OpenCodeAnalyzer {
 	if config.ContainerName == "" {
 		config.ContainerName = "opencode-selfhealing"
 	}
+	if config.Image == "" {
+		config.Image = config.ContainerName
+	}
 
 	// Check if OpenCode is enabled via environment
 	enabled := config.Enabled
@@ -40,23 +85,48 @@ func NewOpenCodeAnalyzer(config OpenCodeConfig) *OpenCodeAnalyzer {
 		enabled = true
 	}
 
-	return &OpenCodeAnalyzer{
+	collector := config.Collector
+	if collector == nil {
+		collector = metrics.NewNoop()
+	}
+
+	a := &OpenCodeAnalyzer{
 		containerName: config.ContainerName,
 		enabled:       enabled,
+		autoStart:     config.ContainerAutoStart,
+		image:         config.Image,
+		workspaceDir:  config.WorkspaceDir,
+		collector:     collector,
+	}
+
+	if !enabled {
+		return a
 	}
+
+	docker, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		log.Printf("failed to build docker client, opencode analysis disabled: %v", err)
+		a.enabled = false
+		return a
+	}
+	a.docker = docker
+
+	return a
 }
 
-// AnalyzeError triggers async analysis via the OpenCode Docker container
-func (a *OpenCodeAnalyzer) AnalyzeError(errCtx ErrorContext) error {
+// AnalyzeError triggers async analysis via the OpenCode Docker container.
+// ctx bounds the synchronous preflight (confirming, or auto-starting, the
+// container); the analysis itself runs in the background on its own
+// timeout, since AnalyzeError is documented to return immediately.
+func (a *OpenCodeAnalyzer) AnalyzeError(ctx context.Context, errCtx ErrorContext) error {
 	if !a.enabled {
 		log.Printf("[%s] OpenCode analysis skipped (disabled)", errCtx.ErrorID)
 		return nil
 	}
 
-	// Check if container is running
-	if !a.isContainerRunning() {
-		log.Printf("[%s] OpenCode container not running, skipping analysis", errCtx.ErrorID)
-		return fmt.Errorf("opencode container not running")
+	if err := a.ensureContainer(ctx); err != nil {
+		log.Printf("[%s] OpenCode container unavailable, skipping analysis: %v", errCtx.ErrorID, err)
+		return fmt.Errorf("opencode container unavailable: %w", err)
 	}
 
 	// Build the analysis payload
@@ -90,41 +160,174 @@ func (a *OpenCodeAnalyzer) AnalyzeError(errCtx ErrorContext) error {
 	return nil
 }
 
-// isContainerRunning checks if the OpenCode container is running
-func (a *OpenCodeAnalyzer) isContainerRunning() bool {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+// ensureContainer confirms the OpenCode container is running, auto-starting
+// it (pulling its image first, if necessary) when ContainerAutoStart is set
+// and it isn't found or isn't running.
+func (a *OpenCodeAnalyzer) ensureContainer(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "docker", "inspect", "-f", "{{.State.Running}}", a.containerName)
-	output, err := cmd.Output()
+	inspect, err := a.docker.ContainerInspect(ctx, a.containerName)
+	if err == nil {
+		if inspect.State != nil && inspect.State.Running {
+			return nil
+		}
+		if !a.autoStart {
+			return fmt.Errorf("container %q is not running", a.containerName)
+		}
+		return a.docker.ContainerStart(ctx, inspect.ID, container.StartOptions{})
+	}
+
+	if !client.IsErrNotFound(err) {
+		return fmt.Errorf("failed to inspect container %q: %w", a.containerName, err)
+	}
+	if !a.autoStart {
+		return fmt.Errorf("container %q not found and auto-start disabled", a.containerName)
+	}
+
+	return a.createAndStartContainer(ctx)
+}
+
+// createAndStartContainer pulls a.image and creates/starts a.containerName
+// from it, mounting a.workspaceDir (if set) at /workspace so the agent can
+// inspect source.
+func (a *OpenCodeAnalyzer) createAndStartContainer(ctx context.Context) error {
+	pullResp, err := a.docker.ImagePull(ctx, a.image, types.ImagePullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull image %q: %w", a.image, err)
+	}
+	defer pullResp.Close()
+	if _, err := io.Copy(io.Discard, pullResp); err != nil {
+		return fmt.Errorf("failed to read image pull progress for %q: %w", a.image, err)
+	}
+
+	var hostConfig *container.HostConfig
+	if a.workspaceDir != "" {
+		hostConfig = &container.HostConfig{
+			Binds: []string{a.workspaceDir + ":/workspace:ro"},
+		}
+	}
+
+	created, err := a.docker.ContainerCreate(ctx, &container.Config{Image: a.image}, hostConfig, nil, nil, a.containerName)
 	if err != nil {
-		return false
+		return fmt.Errorf("failed to create container %q from image %q: %w", a.containerName, a.image, err)
+	}
+
+	if err := a.docker.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start container %q: %w", a.containerName, err)
 	}
 
-	return string(bytes.TrimSpace(output)) == "true"
+	return nil
 }
 
-// executeAnalysis runs the analysis script in the container
+// executeAnalysis runs the analysis script in the container via
+// ContainerExecCreate/ContainerExecAttach, streaming stdout/stderr into the
+// log sink line-by-line instead of buffering the whole run.
 func (a *OpenCodeAnalyzer) executeAnalysis(errorID, payloadB64 string) {
+	start := time.Now()
+	a.collector.IncAnalyzerInflight("opencode")
+	defer a.collector.DecAnalyzerInflight("opencode")
+	outcome := "failure"
+	defer func() { a.collector.ObserveAnalyzerRun("opencode", outcome, time.Since(start)) }()
+
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "docker", "exec", a.containerName, "/app/analyze.sh", payloadB64)
+	execID, err := a.docker.ContainerExecCreate(ctx, a.containerName, types.ExecConfig{
+		Cmd:          []string{"/app/analyze.sh", payloadB64},
+		AttachStdout: true,
+		AttachStderr: true,
+	})
+	if err != nil {
+		log.Printf("[%s] OpenCode analysis failed to create exec: %v", errorID, err)
+		return
+	}
 
-	output, err := cmd.CombinedOutput()
+	attach, err := a.docker.ContainerExecAttach(ctx, execID.ID, types.ExecStartCheck{})
+	if err != nil {
+		log.Printf("[%s] OpenCode analysis failed to attach exec: %v", errorID, err)
+		return
+	}
+	defer attach.Close()
+
+	stdoutR, stdoutW := io.Pipe()
+	stderrR, stderrW := io.Pipe()
+	go func() {
+		_, copyErr := stdcopy.StdCopy(stdoutW, stderrW, attach.Reader)
+		stdoutW.CloseWithError(copyErr)
+		stderrW.CloseWithError(copyErr)
+	}()
+
+	done := make(chan struct{})
+	go streamLines(errorID, "stdout", stdoutR, done)
+	go streamLines(errorID, "stderr", stderrR, done)
+	<-done
+	<-done
+
+	result, err := a.docker.ContainerExecInspect(ctx, execID.ID)
 	if err != nil {
-		log.Printf("[%s] OpenCode analysis failed: %v\nOutput: %s", errorID, err, string(output))
+		log.Printf("[%s] OpenCode analysis failed to inspect exec result: %v", errorID, err)
+		return
+	}
+	if result.ExitCode != 0 {
+		log.Printf("[%s] OpenCode analysis exited with code %d", errorID, result.ExitCode)
 		return
 	}
 
-	log.Printf("[%s] OpenCode analysis completed:\n%s", errorID, string(output))
+	outcome = "success"
+	log.Printf("[%s] OpenCode analysis completed", errorID)
 }
 
-// WebhookAnalyzer implements ErrorAnalyzer using a webhook endpoint
+// streamLines logs each line read from r as it arrives, prefixed with
+// errorID and stream, and signals done once r is exhausted.
+func streamLines(errorID, stream string, r io.Reader, done chan<- struct{}) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		log.Printf("[%s] OpenCode %s: %s", errorID, stream, scanner.Text())
+	}
+	done <- struct{}{}
+}
+
+// DeadLetterSink persists webhook payloads that exhausted their retry
+// budget, so an operator can inspect or replay them later instead of them
+// silently vanishing. repository.SQLiteRepository implements this.
+type DeadLetterSink interface {
+	CreateWebhookDeadLetter(dl *model.WebhookDeadLetter) (int64, error)
+}
+
+// webhookRetryConfig bounds WebhookAnalyzer's exponential-backoff retry
+// loop. Backoff starts at webhookRetryInitialInterval and doubles up to
+// webhookRetryMaxInterval, with full jitter; the loop gives up once
+// webhookRetryMaxElapsed has passed since the first attempt.
+const (
+	webhookRetryInitialInterval = 500 * time.Millisecond
+	webhookRetryMaxInterval     = 30 * time.Second
+	webhookRetryMaxElapsed      = 10 * time.Minute
+)
+
+// webhookError wraps a failed webhook delivery attempt with enough context
+// for sendWebhook's retry loop to decide whether to keep trying and how
+// long to wait before the next attempt.
+type webhookError struct {
+	err        error
+	retryable  bool
+	retryAfter time.Duration
+}
+
+func (e *webhookError) Error() string { return e.err.Error() }
+
+// WebhookAnalyzer implements ErrorAnalyzer using a webhook endpoint. Unlike
+// OpenCodeAnalyzer's Docker container, the receiving endpoint is someone
+// else's service, so delivery gets its own retry budget (see
+// webhookRetryConfig) and a DeadLetterSink for attempts that never succeed.
 type WebhookAnalyzer struct {
-	webhookURL string
-	apiKey     string
-	enabled    bool
+	webhookURL   string
+	apiKey       string
+	enabled      bool
+	client       *http.Client
+	signRequests bool
+	dlq          DeadLetterSink
+	collector    metrics.Collector
 }
 
 // WebhookConfig holds configuration for the webhook analyzer
@@ -132,6 +335,21 @@ type WebhookConfig struct {
 	WebhookURL string
 	APIKey     string
 	Enabled    bool
+	// Client is the HTTP client used to deliver webhooks. Defaults to
+	// http.DefaultClient.
+	Client *http.Client
+	// SignRequests, if true, sends the payload signature in an
+	// X-Signature-256 header (computed via HMAC-SHA256 over the raw body
+	// using APIKey as the secret) instead of sending APIKey directly in an
+	// X-API-Key header.
+	SignRequests bool
+	// DeadLetterSink, if set, receives payloads that exhaust the retry
+	// budget so they can be inspected or replayed later.
+	DeadLetterSink DeadLetterSink
+	// Collector records feedback_analyzer_duration_seconds and
+	// feedback_analyzer_inflight for this analyzer. Defaults to
+	// metrics.NewNoop() if nil.
+	Collector metrics.Collector
 }
 
 // NewWebhookAnalyzer creates a new WebhookAnalyzer
@@ -143,6 +361,13 @@ func NewWebhookAnalyzer(config WebhookConfig) *WebhookAnalyzer {
 	if config.APIKey == "" {
 		config.APIKey = os.Getenv("OPENCODE_WEBHOOK_API_KEY")
 	}
+	if config.Client == nil {
+		config.Client = http.DefaultClient
+	}
+	collector := config.Collector
+	if collector == nil {
+		collector = metrics.NewNoop()
+	}
 
 	enabled := config.Enabled
 	if config.WebhookURL != "" {
@@ -150,14 +375,18 @@ func NewWebhookAnalyzer(config WebhookConfig) *WebhookAnalyzer {
 	}
 
 	return &WebhookAnalyzer{
-		webhookURL: config.WebhookURL,
-		apiKey:     config.APIKey,
-		enabled:    enabled,
+		webhookURL:   config.WebhookURL,
+		apiKey:       config.APIKey,
+		enabled:      enabled,
+		client:       config.Client,
+		signRequests: config.SignRequests,
+		dlq:          config.DeadLetterSink,
+		collector:    collector,
 	}
 }
 
 // AnalyzeError triggers async analysis via webhook
-func (a *WebhookAnalyzer) AnalyzeError(errCtx ErrorContext) error {
+func (a *WebhookAnalyzer) AnalyzeError(ctx context.Context, errCtx ErrorContext) error {
 	if !a.enabled || a.webhookURL == "" {
 		log.Printf("[%s] Webhook analysis skipped (disabled or no URL)", errCtx.ErrorID)
 		return nil
@@ -184,38 +413,126 @@ func (a *WebhookAnalyzer) AnalyzeError(errCtx ErrorContext) error {
 		return fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
-	// Send webhook asynchronously
+	// Send webhook asynchronously, with its own retry budget decoupled
+	// from this request's lifetime.
 	go a.sendWebhook(errCtx.ErrorID, payloadJSON)
 
 	log.Printf("[%s] Webhook analysis triggered", errCtx.ErrorID)
 	return nil
 }
 
-// sendWebhook sends the payload to the webhook URL
+// sendWebhook delivers payload to the webhook URL, retrying with
+// exponential backoff and full jitter on network errors and 5xx/429
+// responses. A Retry-After header on a 429/503 response overrides the
+// computed backoff. Once webhookRetryMaxElapsed has passed, the payload is
+// handed to the DeadLetterSink (if configured) instead of being dropped.
 func (a *WebhookAnalyzer) sendWebhook(errorID string, payload []byte) {
+	start := time.Now()
+	a.collector.IncAnalyzerInflight("webhook")
+	defer a.collector.DecAnalyzerInflight("webhook")
+	outcome := "failure"
+	defer func() { a.collector.ObserveAnalyzerRun("webhook", outcome, time.Since(start)) }()
+
+	interval := webhookRetryInitialInterval
+	var lastErr error
+	var lastAttempt int
+
+	for attempt := 1; ; attempt++ {
+		lastAttempt = attempt
+		webhookErr := a.postWebhook(payload)
+		if webhookErr == nil {
+			outcome = "success"
+			log.Printf("[%s] Webhook delivered (attempt %d)", errorID, attempt)
+			return
+		}
+
+		lastErr = webhookErr
+		log.Printf("[%s] Webhook attempt %d failed: %v", errorID, attempt, webhookErr)
+
+		if !webhookErr.retryable {
+			break
+		}
+		if time.Since(start) >= webhookRetryMaxElapsed {
+			log.Printf("[%s] Webhook retry budget exhausted after %d attempts", errorID, attempt)
+			break
+		}
+
+		wait := webhookErr.retryAfter
+		if wait <= 0 {
+			wait = time.Duration(rand.Int63n(int64(interval)))
+			interval *= 2
+			if interval > webhookRetryMaxInterval {
+				interval = webhookRetryMaxInterval
+			}
+		}
+		time.Sleep(wait)
+	}
+
+	if a.dlq == nil {
+		log.Printf("[%s] Webhook permanently failed, no dead-letter sink configured: %v", errorID, lastErr)
+		return
+	}
+
+	dl := &model.WebhookDeadLetter{
+		ErrorID:     errorID,
+		WebhookURL:  a.webhookURL,
+		PayloadJSON: string(payload),
+		LastError:   lastErr.Error(),
+		Attempts:    lastAttempt,
+	}
+	if _, err := a.dlq.CreateWebhookDeadLetter(dl); err != nil {
+		log.Printf("[%s] Failed to dead-letter webhook payload: %v", errorID, err)
+	}
+}
+
+// postWebhook makes a single delivery attempt. 2xx is success; network
+// errors and 429/5xx are retryable (honoring Retry-After if present);
+// everything else (other 4xx, or a request that can't be built) is not.
+func (a *WebhookAnalyzer) postWebhook(payload []byte) *webhookError {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// Use curl for simplicity (could use net/http but this matches existing patterns)
-	args := []string{
-		"-s", "-X", "POST",
-		"-H", "Content-Type: application/json",
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.webhookURL, bytes.NewReader(payload))
+	if err != nil {
+		return &webhookError{err: fmt.Errorf("failed to build request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	if a.signRequests {
+		mac := hmac.New(sha256.New, []byte(a.apiKey))
+		mac.Write(payload)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	} else if a.apiKey != "" {
+		req.Header.Set("X-API-Key", a.apiKey)
 	}
 
-	if a.apiKey != "" {
-		args = append(args, "-H", fmt.Sprintf("X-API-Key: %s", a.apiKey))
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return &webhookError{err: fmt.Errorf("request failed: %w", err), retryable: true}
 	}
+	defer resp.Body.Close()
 
-	args = append(args, "-d", string(payload), a.webhookURL)
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
 
-	cmd := exec.CommandContext(ctx, "curl", args...)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		log.Printf("[%s] Webhook failed: %v\nOutput: %s", errorID, err, string(output))
-		return
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	werr := &webhookError{
+		err:       fmt.Errorf("webhook returned %d: %s", resp.StatusCode, string(body)),
+		retryable: retryable,
+	}
+
+	if retryable {
+		if ra := resp.Header.Get("Retry-After"); ra != "" {
+			if seconds, err := strconv.Atoi(ra); err == nil {
+				werr.retryAfter = time.Duration(seconds) * time.Second
+			}
+		}
 	}
 
-	log.Printf("[%s] Webhook completed: %s", errorID, string(output))
+	return werr
 }
 
 // CompositeAnalyzer combines multiple analyzers
@@ -229,10 +546,10 @@ func NewCompositeAnalyzer(analyzers ...ErrorAnalyzer) *CompositeAnalyzer {
 }
 
 // AnalyzeError triggers analysis on all configured analyzers
-func (a *CompositeAnalyzer) AnalyzeError(errCtx ErrorContext) error {
+func (a *CompositeAnalyzer) AnalyzeError(ctx context.Context, errCtx ErrorContext) error {
 	var lastErr error
 	for _, analyzer := range a.analyzers {
-		if err := analyzer.AnalyzeError(errCtx); err != nil {
+		if err := analyzer.AnalyzeError(ctx, errCtx); err != nil {
 			lastErr = err
 		}
 	}
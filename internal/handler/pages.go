@@ -11,8 +11,13 @@
 package handler
 
 import (
+	"encoding/csv"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
 )
 
 // HandleDemo renders the demo page.
@@ -21,25 +26,98 @@ func (h *FeedbackHandler) HandleDemo(w http.ResponseWriter, r *http.Request) {
 	h.render(w, r, "demo.html", nil)
 }
 
-// HandleFeedbackList renders a table of all feedback submissions.
+// feedbackListView is what HandleFeedbackList hands to feedback_list.html:
+// the page of results plus the cursors the "Next"/"Prev" links need to
+// carry forward, and the filters so the template can re-populate its form.
+type feedbackListView struct {
+	Items      []*model.Feedback
+	NextCursor string
+	PrevCursor string
+	Filters    map[string]string
+}
+
+// HandleFeedbackList renders a table of feedback submissions, paginated by
+// cursor (see repository.ListOptions) and narrowed by the same query params
+// as HandleList (see parseListOptions).
 // Endpoint: GET /feedback
 func (h *FeedbackHandler) HandleFeedbackList(w http.ResponseWriter, r *http.Request) {
 	// -------------------------------------------------------------------------
 	// 1. DATA FETCHING
 	// -------------------------------------------------------------------------
 
-	// We fetch the latest 100 items.
-	feedbacks, err := h.repo.List(100, 0)
+	opts := parseListOptions(r)
+	filterLabel := "browse"
+	if opts.Query != "" {
+		filterLabel = "search"
+	}
+
+	// This page runs the same FTS5 MATCH query as HandleList/HandleSearch
+	// when ?q= is set, so it shares their rate limiter rather than giving
+	// an anonymous client a third, unthrottled way to hit it.
+	if opts.Query != "" && !h.searchLimiter.allow(clientIP(r)) {
+		http.Error(w, "Too many search requests, please slow down", http.StatusTooManyRequests)
+		return
+	}
+
+	start := time.Now()
+	feedbacks, nextCursor, prevCursor, err := h.repo.ListFiltered(opts)
+	h.collector.ObserveListQueryDuration(filterLabel, time.Since(start))
 	if err != nil {
 		http.Error(w, "Failed to load feedback", http.StatusInternalServerError)
 		return
 	}
 
 	// -------------------------------------------------------------------------
-	// 2. TEMPLATE EXECUTION
+	// 2. CSV EXPORT
+	// -------------------------------------------------------------------------
+
+	// Triage teams export the current filtered view straight from the list
+	// page rather than going through the JSON API and a spreadsheet import.
+	if strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		h.writeFeedbackCSV(w, feedbacks)
+		return
+	}
+
+	// -------------------------------------------------------------------------
+	// 3. TEMPLATE EXECUTION
 	// -------------------------------------------------------------------------
 
-	h.render(w, r, "feedback_list.html", feedbacks)
+	h.render(w, r, "feedback_list.html", feedbackListView{
+		Items:      feedbacks,
+		NextCursor: nextCursor,
+		PrevCursor: prevCursor,
+		Filters: map[string]string{
+			"status":             opts.Status,
+			"priority":           opts.Priority,
+			"category":           opts.Category,
+			"type":               opts.Type,
+			"predicted_priority": opts.PredictedPriority,
+			"q":                  opts.Query,
+		},
+	})
+}
+
+// writeFeedbackCSV streams feedbacks as CSV, one row per record, for the
+// Accept: text/csv code path.
+func (h *FeedbackHandler) writeFeedbackCSV(w http.ResponseWriter, feedbacks []*model.Feedback) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="feedback.csv"`)
+
+	cw := csv.NewWriter(w)
+	defer cw.Flush()
+
+	cw.Write([]string{
+		"id", "created_at", "type", "status", "priority", "category",
+		"predicted_priority", "title", "description", "url",
+	})
+	for _, f := range feedbacks {
+		cw.Write([]string{
+			strconv.FormatInt(f.ID, 10),
+			f.CreatedAt.UTC().Format("2006-01-02T15:04:05Z"),
+			f.Type, f.Status, f.Priority, f.Category, f.PredictedPriority,
+			f.Title, f.Description, f.URL,
+		})
+	}
 }
 
 // HandleFeedbackDetail renders the detailed view for a single feedback item.
@@ -0,0 +1,88 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// rate_limiter.go rate-limits a single request bucket per client IP. It
+// backs HandleList's full-text search path (see feedback.go): FTS5 queries
+// are expensive enough that an anonymous scraper hammering ?q= shouldn't be
+// able to starve normal listing traffic, the same cheap/expensive bucket
+// split web layers like Skia Gold's use for their search endpoints.
+package handler
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// ipRateLimiterIdleTTL is how long an IP's bucket is kept after its last
+// request before cleanupLoop reclaims it, so a long-running process doesn't
+// accumulate one entry per distinct visitor forever.
+const ipRateLimiterIdleTTL = 10 * time.Minute
+
+// ipRateLimiter is a per-client-IP token bucket.
+type ipRateLimiter struct {
+	rate  rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*ipBucket
+}
+
+type ipBucket struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// newIPRateLimiter builds a limiter allowing r sustained requests/sec per IP
+// with burst capacity, and starts its background cleanup goroutine.
+func newIPRateLimiter(r rate.Limit, burst int) *ipRateLimiter {
+	l := &ipRateLimiter{
+		rate:     r,
+		burst:    burst,
+		limiters: make(map[string]*ipBucket),
+	}
+	go l.cleanupLoop()
+	return l
+}
+
+// allow reports whether ip's bucket has a token available right now.
+func (l *ipRateLimiter) allow(ip string) bool {
+	l.mu.Lock()
+	b, ok := l.limiters[ip]
+	if !ok {
+		b = &ipBucket{limiter: rate.NewLimiter(l.rate, l.burst)}
+		l.limiters[ip] = b
+	}
+	b.lastSeen = time.Now()
+	l.mu.Unlock()
+
+	return b.limiter.Allow()
+}
+
+// cleanupLoop evicts buckets idle for longer than ipRateLimiterIdleTTL.
+func (l *ipRateLimiter) cleanupLoop() {
+	ticker := time.NewTicker(ipRateLimiterIdleTTL)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-ipRateLimiterIdleTTL)
+		l.mu.Lock()
+		for ip, b := range l.limiters {
+			if b.lastSeen.Before(cutoff) {
+				delete(l.limiters, ip)
+			}
+		}
+		l.mu.Unlock()
+	}
+}
+
+// clientIP extracts the request's client IP for rate-limiting purposes.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
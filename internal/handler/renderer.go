@@ -0,0 +1,110 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// renderer.go extracts FeedbackHandler's template lookup/execution behind a
+// Renderer interface, so NewFeedbackHandler can swap in a DevRenderer (that
+// re-parses from disk on every request) without touching render() or any
+// page handler. See NewRenderer.
+package handler
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+)
+
+// Renderer executes a named page template, either in full (Render) or a
+// single named block of it (RenderFragment, for HTMX fragment/OOB swaps).
+type Renderer interface {
+	Render(w http.ResponseWriter, r *http.Request, name string, data interface{}) error
+	RenderFragment(w http.ResponseWriter, r *http.Request, name, block string, data interface{}) error
+	// Have reports whether name is a known page template.
+	Have(name string) bool
+}
+
+// NewRenderer returns the production Renderer: every page in pages is
+// parsed once (base.html cloned per page, per the existing base+page
+// inheritance pattern) and cached for the handler's lifetime. Use
+// NewDevRenderer instead for a renderer that re-parses from disk on every
+// request, for iterating on templates without restarting the server.
+func NewRenderer(templateFS fs.FS, funcMap template.FuncMap, pages []string) Renderer {
+	baseTmpl := template.Must(template.New("base.html").Funcs(funcMap).ParseFS(templateFS, "base.html"))
+
+	templates := make(map[string]*template.Template, len(pages))
+	for _, page := range pages {
+		clone := template.Must(baseTmpl.Clone())
+		templates[page] = template.Must(clone.ParseFS(templateFS, page))
+	}
+
+	return &cachedRenderer{templates: templates}
+}
+
+// cachedRenderer is Renderer's production implementation: templates are
+// parsed once at construction and reused for every request.
+type cachedRenderer struct {
+	templates map[string]*template.Template
+}
+
+func (c *cachedRenderer) Have(name string) bool {
+	_, ok := c.templates[name]
+	return ok
+}
+
+func (c *cachedRenderer) Render(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
+	return c.RenderFragment(w, r, name, "base.html", data)
+}
+
+func (c *cachedRenderer) RenderFragment(w http.ResponseWriter, r *http.Request, name, block string, data interface{}) error {
+	tmpl, ok := c.templates[name]
+	if !ok {
+		return fmt.Errorf("template %s not found", name)
+	}
+	return tmpl.ExecuteTemplate(w, block, data)
+}
+
+// DevRenderer re-parses base.html and the requested page from templateFS on
+// every call, so edits to .html files on disk take effect on the next
+// request with no server restart. Only used when FEEDBACK_DEV=1 - the
+// reparsing cost (and the loss of template.Must's fail-fast-on-startup
+// behavior) isn't worth paying in production.
+type DevRenderer struct {
+	templateFS fs.FS
+	funcMap    template.FuncMap
+	pages      map[string]struct{}
+}
+
+// NewDevRenderer builds a DevRenderer that accepts the same page names
+// NewRenderer was given.
+func NewDevRenderer(templateFS fs.FS, funcMap template.FuncMap, pages []string) *DevRenderer {
+	set := make(map[string]struct{}, len(pages))
+	for _, page := range pages {
+		set[page] = struct{}{}
+	}
+	return &DevRenderer{templateFS: templateFS, funcMap: funcMap, pages: set}
+}
+
+func (d *DevRenderer) Have(name string) bool {
+	_, ok := d.pages[name]
+	return ok
+}
+
+func (d *DevRenderer) Render(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
+	return d.RenderFragment(w, r, name, "base.html", data)
+}
+
+func (d *DevRenderer) RenderFragment(w http.ResponseWriter, r *http.Request, name, block string, data interface{}) error {
+	if !d.Have(name) {
+		return fmt.Errorf("template %s not found", name)
+	}
+
+	base, err := template.New("base.html").Funcs(d.funcMap).ParseFS(d.templateFS, "base.html")
+	if err != nil {
+		return fmt.Errorf("parsing base.html: %w", err)
+	}
+	tmpl, err := base.ParseFS(d.templateFS, name)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", name, err)
+	}
+
+	return tmpl.ExecuteTemplate(w, block, data)
+}
@@ -0,0 +1,63 @@
+// Package handler provides HTTP handlers for the Feedback Service.
+//
+// selfhealing_jobs.go exposes the jobs table backing selfhealing.Trigger's
+// durable queue as a small read-only API: browse queued/running/finished
+// jobs and inspect one, mirroring admin_errors.go's pagination shape.
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// HandleJobsList processes GET /api/selfhealing/jobs (Pagination).
+func (h *FeedbackHandler) HandleJobsList(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	offset := 0
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	if o := r.URL.Query().Get("offset"); o != "" {
+		if parsed, err := strconv.Atoi(o); err == nil && parsed >= 0 {
+			offset = parsed
+		}
+	}
+
+	jobs, err := h.repo.ListJobs(limit, offset)
+	if err != nil {
+		log.Printf("Failed to list self-healing jobs: %v", err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve jobs", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(jobs)
+}
+
+// HandleJobDetail processes GET /api/selfhealing/jobs/{id}.
+func (h *FeedbackHandler) HandleJobDetail(w http.ResponseWriter, r *http.Request) {
+	idStr := r.PathValue("id")
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid job ID", "")
+		return
+	}
+
+	job, err := h.repo.GetJobByID(id)
+	if err != nil {
+		log.Printf("Failed to get self-healing job %d: %v", id, err)
+		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve job", "")
+		return
+	}
+	if job == nil {
+		h.writeError(w, http.StatusNotFound, "Job not found", "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(job)
+}
@@ -0,0 +1,198 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// Collector is the interface the HTTP middleware and selfhealing.Trigger
+// record metrics through, instead of depending on Registry/*Vec directly -
+// the same "small consumer-owned interface" pattern as handler.ErrorStore
+// and selfhealing.JobStore. A fake implementation can assert exactly which
+// calls were made without standing up a live registry.
+type Collector interface {
+	// ObserveHTTPRequest records one completed HTTP request.
+	ObserveHTTPRequest(method, path string, status int, duration time.Duration)
+	// ObserveSelfHealingJob records one terminal (done or dead) self-healing
+	// job outcome.
+	ObserveSelfHealingJob(mode, result string, duration time.Duration)
+	// SetSelfHealingCooldownSeconds reports how much of the trigger cooldown
+	// remains right now.
+	SetSelfHealingCooldownSeconds(seconds float64)
+	// SetSelfHealingRunning reports how many self-healing jobs are currently
+	// executing.
+	SetSelfHealingRunning(n int)
+	// SetSelfHealingContainerUp reports whether the named opencode container
+	// is currently running.
+	SetSelfHealingContainerUp(name string, up bool)
+
+	// ObserveFeedbackSubmission records one HandleSubmit call, by feedback
+	// type and outcome ("created", "rejected", or "error").
+	ObserveFeedbackSubmission(feedbackType, status string)
+	// ObserveAnalyzerRun records one completed ErrorAnalyzer dispatch, by
+	// analyzer name (e.g. "opencode", "webhook") and outcome ("success" or
+	// "failure").
+	ObserveAnalyzerRun(analyzer, outcome string, duration time.Duration)
+	// IncAnalyzerInflight and DecAnalyzerInflight bracket an analyzer's
+	// in-flight goroutine, so the gauge reflects concurrent runs rather
+	// than a single absolute value set at one point in time.
+	IncAnalyzerInflight(analyzer string)
+	DecAnalyzerInflight(analyzer string)
+	// ObserveRenderDuration records one h.render call, by template name.
+	ObserveRenderDuration(template string, duration time.Duration)
+	// ObserveGuardBlock records one request rejected by the prompt-injection
+	// guard, by reason.
+	ObserveGuardBlock(reason string)
+	// ObserveListQueryDuration records one ListFiltered call issued on behalf
+	// of a listing request, by filter ("search" for the FTS path, "browse"
+	// otherwise).
+	ObserveListQueryDuration(filter string, duration time.Duration)
+}
+
+// PrometheusCollector is the Collector backed by a live Registry, serving
+// its current state at /metrics via Handler.
+type PrometheusCollector struct {
+	registry *Registry
+
+	httpRequestsTotal   *CounterVec
+	httpRequestDuration *HistogramVec
+
+	jobsTotal       *CounterVec
+	jobDuration     *HistogramVec
+	cooldownSeconds *GaugeVec
+	running         *GaugeVec
+	containerUp     *GaugeVec
+
+	submissionsTotal *CounterVec
+	analyzerDuration *HistogramVec
+	analyzerInflight *GaugeVec
+	renderDuration   *HistogramVec
+
+	guardBlocksTotal  *CounterVec
+	listQueryDuration *HistogramVec
+}
+
+// NewPrometheusCollector builds a Collector with a fresh Registry and all
+// metric families this service emits already registered.
+func NewPrometheusCollector() *PrometheusCollector {
+	registry := NewRegistry()
+
+	return &PrometheusCollector{
+		registry: registry,
+
+		httpRequestsTotal: registry.Counter(NewCounterVec(
+			"http_requests_total", "Total number of HTTP requests by method, path, and status.")),
+		httpRequestDuration: registry.Histogram(NewHistogramVec(
+			"http_request_duration_seconds", "HTTP request handling duration in seconds.")),
+
+		jobsTotal: registry.Counter(NewCounterVec(
+			"selfhealing_jobs_total", "Total number of self-healing jobs that reached a terminal state, by mode and result.")),
+		jobDuration: registry.Histogram(NewHistogramVec(
+			"selfhealing_job_duration_seconds", "Self-healing job execution duration in seconds, by mode.")),
+		cooldownSeconds: registry.Gauge(NewGaugeVec(
+			"selfhealing_cooldown_seconds", "Seconds remaining before the self-healing cooldown allows another trigger.")),
+		running: registry.Gauge(NewGaugeVec(
+			"selfhealing_running", "Number of self-healing jobs currently executing.")),
+		containerUp: registry.Gauge(NewGaugeVec(
+			"selfhealing_container_up", "Whether the named opencode container is currently running (1) or not (0).")),
+
+		submissionsTotal: registry.Counter(NewCounterVec(
+			"feedback_submissions_total", "Total number of feedback submissions, by type and outcome.")),
+		analyzerDuration: registry.Histogram(NewHistogramVec(
+			"feedback_analyzer_duration_seconds", "ErrorAnalyzer dispatch duration in seconds, by analyzer and outcome.")),
+		analyzerInflight: registry.Gauge(NewGaugeVec(
+			"feedback_analyzer_inflight", "Number of analyzer goroutines currently running, by analyzer.")),
+		renderDuration: registry.Histogram(NewHistogramVec(
+			"feedback_render_duration_seconds", "HTML template render duration in seconds, by template.")),
+
+		guardBlocksTotal: registry.Counter(NewCounterVec(
+			"feedback_guard_blocks_total", "Total number of feedback submissions rejected by the prompt-injection guard, by reason.")),
+		listQueryDuration: registry.Histogram(NewHistogramVec(
+			"feedback_list_query_duration_seconds", "ListFiltered call duration in seconds, by filter.")),
+	}
+}
+
+func (c *PrometheusCollector) ObserveHTTPRequest(method, path string, status int, duration time.Duration) {
+	labels := map[string]string{"method": method, "path": path, "status": http.StatusText(status)}
+	c.httpRequestsTotal.Inc(labels)
+	c.httpRequestDuration.Observe(map[string]string{"method": method, "path": path}, duration.Seconds())
+}
+
+func (c *PrometheusCollector) ObserveSelfHealingJob(mode, result string, duration time.Duration) {
+	c.jobsTotal.Inc(map[string]string{"mode": mode, "result": result})
+	c.jobDuration.Observe(map[string]string{"mode": mode}, duration.Seconds())
+}
+
+func (c *PrometheusCollector) SetSelfHealingCooldownSeconds(seconds float64) {
+	c.cooldownSeconds.Set(nil, seconds)
+}
+
+func (c *PrometheusCollector) SetSelfHealingRunning(n int) {
+	c.running.Set(nil, float64(n))
+}
+
+func (c *PrometheusCollector) SetSelfHealingContainerUp(name string, up bool) {
+	value := 0.0
+	if up {
+		value = 1.0
+	}
+	c.containerUp.Set(map[string]string{"name": name}, value)
+}
+
+func (c *PrometheusCollector) ObserveFeedbackSubmission(feedbackType, status string) {
+	c.submissionsTotal.Inc(map[string]string{"type": feedbackType, "status": status})
+}
+
+func (c *PrometheusCollector) ObserveAnalyzerRun(analyzer, outcome string, duration time.Duration) {
+	c.analyzerDuration.Observe(map[string]string{"analyzer": analyzer, "outcome": outcome}, duration.Seconds())
+}
+
+func (c *PrometheusCollector) IncAnalyzerInflight(analyzer string) {
+	c.analyzerInflight.Add(map[string]string{"analyzer": analyzer}, 1)
+}
+
+func (c *PrometheusCollector) DecAnalyzerInflight(analyzer string) {
+	c.analyzerInflight.Add(map[string]string{"analyzer": analyzer}, -1)
+}
+
+func (c *PrometheusCollector) ObserveRenderDuration(template string, duration time.Duration) {
+	c.renderDuration.Observe(map[string]string{"template": template}, duration.Seconds())
+}
+
+func (c *PrometheusCollector) ObserveGuardBlock(reason string) {
+	c.guardBlocksTotal.Inc(map[string]string{"reason": reason})
+}
+
+func (c *PrometheusCollector) ObserveListQueryDuration(filter string, duration time.Duration) {
+	c.listQueryDuration.Observe(map[string]string{"filter": filter}, duration.Seconds())
+}
+
+// Handler serves the registry's current state in Prometheus text exposition
+// format.
+func (c *PrometheusCollector) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		c.registry.WriteTo(w)
+	}
+}
+
+// noopCollector discards every observation. Used as the default Collector
+// wherever a caller doesn't wire in a real one (e.g. NewTrigger(config,
+// store, nil)), so call sites never need a nil check before recording.
+type noopCollector struct{}
+
+// NewNoop returns a Collector that discards everything it's given.
+func NewNoop() Collector { return noopCollector{} }
+
+func (noopCollector) ObserveHTTPRequest(method, path string, status int, duration time.Duration) {}
+func (noopCollector) ObserveSelfHealingJob(mode, result string, duration time.Duration)          {}
+func (noopCollector) SetSelfHealingCooldownSeconds(seconds float64)                              {}
+func (noopCollector) SetSelfHealingRunning(n int)                                                {}
+func (noopCollector) SetSelfHealingContainerUp(name string, up bool)                             {}
+func (noopCollector) ObserveFeedbackSubmission(feedbackType, status string)                      {}
+func (noopCollector) ObserveAnalyzerRun(analyzer, outcome string, duration time.Duration)        {}
+func (noopCollector) IncAnalyzerInflight(analyzer string)                                        {}
+func (noopCollector) DecAnalyzerInflight(analyzer string)                                        {}
+func (noopCollector) ObserveRenderDuration(template string, duration time.Duration)              {}
+func (noopCollector) ObserveGuardBlock(reason string)                                            {}
+func (noopCollector) ObserveListQueryDuration(filter string, duration time.Duration)              {}
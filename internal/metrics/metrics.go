@@ -0,0 +1,278 @@
+// Package metrics implements a small Prometheus-compatible metrics registry
+// for the feedback service: labeled counters, histograms, and gauges that
+// render in the text exposition format the /metrics endpoint serves.
+//
+// This is hand-rolled rather than pulled in from a client library, matching
+// the repo's general "pure Go, minimal dependencies" stance (see
+// repository.go's choice of modernc.org/sqlite for the same reason) - the
+// full Prometheus client does a lot more than this service needs.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// labelKey renders a label set as a stable map key, independent of the
+// order callers build the map in.
+func labelKey(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, k+"="+labels[k])
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatLabels renders labels in Prometheus exposition syntax, e.g.
+// `{method="GET",path="/health"}`. Returns "" if there are no labels.
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// CounterVec is a monotonically-increasing counter partitioned by label
+// values.
+type CounterVec struct {
+	name string
+	help string
+
+	mu     sync.Mutex
+	values map[string]float64
+	labels map[string]map[string]string
+}
+
+// NewCounterVec creates an empty counter family. name and help are rendered
+// verbatim in the # TYPE/# HELP exposition lines.
+func NewCounterVec(name, help string) *CounterVec {
+	return &CounterVec{
+		name:   name,
+		help:   help,
+		values: make(map[string]float64),
+		labels: make(map[string]map[string]string),
+	}
+}
+
+// Inc increments the counter for labels by one.
+func (c *CounterVec) Inc(labels map[string]string) {
+	c.Add(labels, 1)
+}
+
+// Add increments the counter for labels by delta.
+func (c *CounterVec) Add(labels map[string]string, delta float64) {
+	key := labelKey(labels)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[key] += delta
+	c.labels[key] = labels
+}
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for key, v := range c.values {
+		fmt.Fprintf(w, "%s%s %v\n", c.name, formatLabels(c.labels[key]), v)
+	}
+}
+
+// GaugeVec is a point-in-time value partitioned by label values.
+type GaugeVec struct {
+	name string
+	help string
+
+	mu     sync.Mutex
+	values map[string]float64
+	labels map[string]map[string]string
+}
+
+// NewGaugeVec creates an empty gauge family.
+func NewGaugeVec(name, help string) *GaugeVec {
+	return &GaugeVec{
+		name:   name,
+		help:   help,
+		values: make(map[string]float64),
+		labels: make(map[string]map[string]string),
+	}
+}
+
+// Set overwrites the gauge's current value for labels.
+func (g *GaugeVec) Set(labels map[string]string, value float64) {
+	key := labelKey(labels)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] = value
+	g.labels[key] = labels
+}
+
+// Add adjusts the gauge's current value for labels by delta, which may be
+// negative. Used for inflight-style gauges where concurrent callers
+// increment on start and decrement on finish rather than setting an
+// absolute value.
+func (g *GaugeVec) Add(labels map[string]string, delta float64) {
+	key := labelKey(labels)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.values[key] += delta
+	g.labels[key] = labels
+}
+
+func (g *GaugeVec) write(w io.Writer) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for key, v := range g.values {
+		fmt.Fprintf(w, "%s%s %v\n", g.name, formatLabels(g.labels[key]), v)
+	}
+}
+
+// defaultBuckets mirrors the Prometheus client library's default histogram
+// buckets extended with a few larger ones, since a self-healing job can
+// legitimately take minutes while an HTTP handler takes milliseconds - both
+// share this bucket set rather than each needing their own.
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 1800}
+
+// histogramState is one label combination's accumulated bucket counts, sum,
+// and count.
+type histogramState struct {
+	bucketCounts []uint64 // cumulative, indexed the same as defaultBuckets
+	sum          float64
+	count        uint64
+}
+
+// HistogramVec is a histogram metric partitioned by label values, bucketed
+// by defaultBuckets.
+type HistogramVec struct {
+	name string
+	help string
+
+	mu     sync.Mutex
+	states map[string]*histogramState
+	labels map[string]map[string]string
+}
+
+// NewHistogramVec creates an empty histogram family.
+func NewHistogramVec(name, help string) *HistogramVec {
+	return &HistogramVec{
+		name:   name,
+		help:   help,
+		states: make(map[string]*histogramState),
+		labels: make(map[string]map[string]string),
+	}
+}
+
+// Observe records one value (e.g. a request duration in seconds) against
+// the histogram for labels.
+func (h *HistogramVec) Observe(labels map[string]string, value float64) {
+	key := labelKey(labels)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	state, ok := h.states[key]
+	if !ok {
+		state = &histogramState{bucketCounts: make([]uint64, len(defaultBuckets))}
+		h.states[key] = state
+		h.labels[key] = labels
+	}
+
+	for i, bound := range defaultBuckets {
+		if value <= bound {
+			state.bucketCounts[i]++
+		}
+	}
+	state.sum += value
+	state.count++
+}
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	for key, state := range h.states {
+		base := h.labels[key]
+		for i, bound := range defaultBuckets {
+			bucketLabels := make(map[string]string, len(base)+1)
+			for k, v := range base {
+				bucketLabels[k] = v
+			}
+			bucketLabels["le"] = fmt.Sprintf("%v", bound)
+			fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(bucketLabels), state.bucketCounts[i])
+		}
+		infLabels := make(map[string]string, len(base)+1)
+		for k, v := range base {
+			infLabels[k] = v
+		}
+		infLabels["le"] = "+Inf"
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, formatLabels(infLabels), state.count)
+		fmt.Fprintf(w, "%s_sum%s %v\n", h.name, formatLabels(base), state.sum)
+		fmt.Fprintf(w, "%s_count%s %d\n", h.name, formatLabels(base), state.count)
+	}
+}
+
+// Registry is the fixed set of metric families the service exposes. Unlike
+// the real Prometheus client, there's no global default registry - callers
+// build one explicitly and thread it through (see metrics.Collector).
+type Registry struct {
+	counters   []*CounterVec
+	gauges     []*GaugeVec
+	histograms []*HistogramVec
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Counter registers and returns c.
+func (r *Registry) Counter(c *CounterVec) *CounterVec {
+	r.counters = append(r.counters, c)
+	return c
+}
+
+// Gauge registers and returns g.
+func (r *Registry) Gauge(g *GaugeVec) *GaugeVec {
+	r.gauges = append(r.gauges, g)
+	return g
+}
+
+// Histogram registers and returns h.
+func (r *Registry) Histogram(h *HistogramVec) *HistogramVec {
+	r.histograms = append(r.histograms, h)
+	return h
+}
+
+// WriteTo renders every registered metric family in Prometheus text
+// exposition format.
+func (r *Registry) WriteTo(w io.Writer) {
+	for _, c := range r.counters {
+		c.write(w)
+	}
+	for _, g := range r.gauges {
+		g.write(w)
+	}
+	for _, h := range r.histograms {
+		h.write(w)
+	}
+}
@@ -132,4 +132,26 @@ type FeedbackResponse struct {
 type ErrorResponse struct {
 	Error   string `json:"error"`
 	Details string `json:"details,omitempty"`
+}
+
+// StoredError is the persisted record of an error captured by
+// handler.ErrorHandler.HandleError. It mirrors handler.ErrorRecord plus the
+// triage fields (Analysis, ResolvedAt) populated after the fact, so an
+// operator can browse, inspect, and replay analysis for past incidents.
+type StoredError struct {
+	ID            int64      `json:"id"`
+	ErrorID       string     `json:"errorId"`
+	TraceID       string     `json:"traceId,omitempty"`
+	Fingerprint   string     `json:"fingerprint,omitempty"`
+	HandlerFile   string     `json:"handlerFile"`
+	HandlerFunc   string     `json:"handlerFunc"`
+	RequestPath   string     `json:"requestPath"`
+	RequestMethod string     `json:"requestMethod"`
+	StatusCode    int        `json:"statusCode"`
+	ErrorMessage  string     `json:"errorMessage"`
+	StackTrace    string     `json:"stackTrace,omitempty"`
+	UserAgent     string     `json:"userAgent,omitempty"`
+	Analysis      string     `json:"analysis,omitempty"`
+	ResolvedAt    *time.Time `json:"resolvedAt,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
 }
\ No newline at end of file
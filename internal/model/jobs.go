@@ -0,0 +1,42 @@
+package model
+
+import "time"
+
+// JobState tracks a self-healing job through its lifecycle: queued ->
+// running -> (done | queued again for retry | dead once retries are
+// exhausted).
+type JobState string
+
+const (
+	JobStateQueued  JobState = "queued"
+	JobStateRunning JobState = "running"
+	JobStateDone    JobState = "done"
+	JobStateFailed  JobState = "failed"
+	JobStateDead    JobState = "dead"
+)
+
+// Job is a persisted unit of self-healing work, queued by
+// selfhealing.Trigger.TriggerAsync and executed by Trigger's worker pool. It
+// survives process restarts so a crash mid-analysis doesn't silently lose
+// the feedback that triggered it (see selfhealing.NewTrigger's startup
+// recovery pass over state='running' rows).
+type Job struct {
+	ID         int64    `json:"id"`
+	FeedbackID int64    `json:"feedbackId"`
+	Mode       string   `json:"mode"`
+	State      JobState `json:"state"`
+	Attempts   int      `json:"attempts"`
+	LastError  string   `json:"lastError,omitempty"`
+	// RequestID is the correlation ID of the HTTP request that enqueued this
+	// job (see internal/requestid), so its worker logs can be tied back to
+	// the originating request.
+	RequestID     string     `json:"requestId,omitempty"`
+	StartedAt     *time.Time `json:"startedAt,omitempty"`
+	FinishedAt    *time.Time `json:"finishedAt,omitempty"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	// ResultJSON is the serialized selfhealing.Result once the job finishes,
+	// so /api/selfhealing/jobs/{id} can show the full outcome without this
+	// package depending on selfhealing.
+	ResultJSON string    `json:"resultJson,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
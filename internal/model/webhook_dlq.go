@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// WebhookDeadLetter is a webhook payload that exhausted its retry budget
+// (see handler.WebhookAnalyzer's exponential-backoff retry loop), captured
+// durably so an operator can inspect or replay it instead of it silently
+// vanishing because the receiving endpoint was down.
+type WebhookDeadLetter struct {
+	ID          int64      `json:"id"`
+	ErrorID     string     `json:"errorId"`
+	WebhookURL  string     `json:"webhookUrl"`
+	PayloadJSON string     `json:"payloadJson"`
+	LastError   string     `json:"lastError"`
+	Attempts    int        `json:"attempts"`
+	ReplayedAt  *time.Time `json:"replayedAt,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+}
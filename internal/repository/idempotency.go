@@ -0,0 +1,170 @@
+// Package repository implements the data persistence layer using SQLite.
+//
+// idempotency.go backs HandleSubmit's Idempotency-Key support: a client
+// retrying a POST /api/feedback after a flaky-network timeout should get
+// back the original response instead of creating a second feedback row and
+// paying for a second self-healing (LLM) run.
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// IdempotencyKeyTTL is how long a reserved Idempotency-Key is honored
+// before idempotencySweepInterval prunes it and a reused key is treated as
+// new again.
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// idempotencyPollInterval and idempotencyPollTimeout bound how long
+// ReserveIdempotencyKey waits for a concurrent duplicate (one that won the
+// INSERT race and is still processing) to finish, before giving up - the
+// same "poll a ticker until the async thing finishes, or time out" shape
+// selfhealing's job executors use to wait out a Kubernetes/Nomad job.
+const (
+	idempotencyPollInterval = 100 * time.Millisecond
+	idempotencyPollTimeout  = 5 * time.Second
+)
+
+// IdempotencyRecord is a stored Idempotency-Key response, replayed verbatim
+// on retry.
+type IdempotencyRecord struct {
+	BodyHash       string
+	ResponseStatus int
+	ResponseBody   string
+}
+
+// ReserveIdempotencyKey atomically claims key for bodyHash.
+//
+//   - If key hasn't been seen before, it's reserved (inserted with a
+//     response_status of 0, meaning "in flight") and ReserveIdempotencyKey
+//     returns (nil, false, nil): the caller does the real work and must
+//     call SaveIdempotencyResponse once it has a response.
+//   - If key was already reserved with the same bodyHash and already has a
+//     stored response, that response is returned for the caller to replay
+//     verbatim.
+//   - If key was already reserved with the same bodyHash but is still in
+//     flight (a concurrent duplicate request beat this one to the INSERT),
+//     ReserveIdempotencyKey polls briefly for it to finish rather than
+//     double-processing.
+//   - If key was already used with a different bodyHash, conflict is true:
+//     the caller should respond 409.
+//
+// The INSERT ... ON CONFLICT DO NOTHING makes the initial claim race-safe
+// across concurrent goroutines without a separate lock.
+func (r *SQLiteRepository) ReserveIdempotencyKey(ctx context.Context, key, bodyHash string) (record *IdempotencyRecord, conflict bool, err error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO idempotency_keys (key, body_hash, response_status, response_body, created_at)
+		VALUES (?, ?, 0, '', ?)
+		ON CONFLICT(key) DO NOTHING`,
+		key, bodyHash, time.Now().UTC())
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+	if reserved, err := res.RowsAffected(); err != nil {
+		return nil, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	} else if reserved == 1 {
+		// We won the race: this is the first arrival for key.
+		return nil, false, nil
+	}
+
+	storedHash, status, body, err := r.readIdempotencyKey(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	if storedHash != bodyHash {
+		return nil, true, nil
+	}
+
+	if status == 0 {
+		status, body, err = r.pollIdempotencyResponse(ctx, key)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	return &IdempotencyRecord{BodyHash: storedHash, ResponseStatus: status, ResponseBody: body}, false, nil
+}
+
+func (r *SQLiteRepository) readIdempotencyKey(ctx context.Context, key string) (bodyHash string, status int, body string, err error) {
+	err = r.db.QueryRowContext(ctx,
+		`SELECT body_hash, response_status, response_body FROM idempotency_keys WHERE key = ?`, key,
+	).Scan(&bodyHash, &status, &body)
+	if err != nil {
+		return "", 0, "", fmt.Errorf("failed to read idempotency key: %w", err)
+	}
+	return bodyHash, status, body, nil
+}
+
+// pollIdempotencyResponse waits for the in-flight request holding key to
+// call SaveIdempotencyResponse, up to idempotencyPollTimeout.
+func (r *SQLiteRepository) pollIdempotencyResponse(ctx context.Context, key string) (int, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, idempotencyPollTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(idempotencyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return 0, "", fmt.Errorf("timed out waiting for idempotency key %q to finish processing", key)
+		case <-ticker.C:
+			_, status, body, err := r.readIdempotencyKey(ctx, key)
+			if err != nil {
+				return 0, "", err
+			}
+			if status != 0 {
+				return status, body, nil
+			}
+		}
+	}
+}
+
+// SaveIdempotencyResponse records the response a request reserved via
+// ReserveIdempotencyKey produced, so a future replay of the same key can
+// return it verbatim.
+func (r *SQLiteRepository) SaveIdempotencyResponse(ctx context.Context, key string, status int, body string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE idempotency_keys SET response_status = ?, response_body = ? WHERE key = ?`,
+		status, body, key)
+	if err != nil {
+		return fmt.Errorf("failed to save idempotency response: %w", err)
+	}
+	return nil
+}
+
+// PruneExpiredIdempotencyKeys deletes keys older than IdempotencyKeyTTL, so
+// the table doesn't grow unbounded and a reused key (after the TTL) is
+// treated as new.
+func (r *SQLiteRepository) PruneExpiredIdempotencyKeys() (int64, error) {
+	cutoff := time.Now().UTC().Add(-IdempotencyKeyTTL)
+	res, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE created_at < ?`, cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune expired idempotency keys: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// idempotencySweepInterval is how often sweepIdempotencyKeysLoop prunes
+// expired keys. An entry can live up to an hour past IdempotencyKeyTTL
+// before a sweep removes it, and until then a reused key is still replayed
+// against it; sweeping more often would mostly be wasted work.
+const idempotencySweepInterval = 1 * time.Hour
+
+// sweepIdempotencyKeysLoop prunes expired idempotency_keys rows on a
+// timer, for the lifetime of the process. Started once from
+// NewSQLiteRepository, the same way ipRateLimiter.cleanupLoop evicts idle
+// rate-limit buckets.
+func (r *SQLiteRepository) sweepIdempotencyKeysLoop() {
+	ticker := time.NewTicker(idempotencySweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := r.PruneExpiredIdempotencyKeys(); err != nil {
+			log.Printf("failed to sweep expired idempotency keys: %v", err)
+		}
+	}
+}
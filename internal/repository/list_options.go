@@ -0,0 +1,283 @@
+// Package repository implements the data persistence layer using SQLite.
+//
+// list_options.go adds keyset ("cursor") pagination to feedback listing, on
+// top of the same triage filters search.go already exposes via
+// SearchFilters. Keyset pagination avoids OFFSET's O(N) scan cost: instead
+// of "skip N rows", each page's cursor encodes the last row it returned, so
+// the next page's WHERE clause can seek straight to it.
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// ListOptions narrows and paginates a ListFiltered call. Zero-valued filter
+// fields are not applied, matching SearchFilters.
+type ListOptions struct {
+	Limit int
+	// Cursor is an opaque, HMAC-signed value from a previous ListFiltered
+	// call's nextCursor/prevCursor (see SQLiteRepository.encodeCursor).
+	// Empty means "start from the newest feedback".
+	Cursor string
+	// Direction is "next" (default, older rows) or "prev" (newer rows,
+	// i.e. paging back toward Cursor's page).
+	Direction string
+
+	Status            string
+	Priority          string
+	Category          string
+	Type              string
+	PredictedPriority string
+	// Since, if set, only includes feedback created at or after this time.
+	Since time.Time
+	// HasAnalysis, if set, narrows to feedback with (true) or without
+	// (false) a stored LLM analysis. Nil means "don't filter on this".
+	HasAnalysis *bool
+	// Query, if set, full-text matches Title/Description (and the other
+	// indexed columns) via the feedback_fts FTS5 table search.go already
+	// maintains.
+	Query string
+}
+
+// encodeCursor packs a row's keyset position into the opaque, HMAC-signed
+// string ListOptions.Cursor expects: a client can hand a cursor straight
+// back to page further, but can't forge one to seek to an arbitrary
+// position since it's signed with r.cursorSecret.
+func (r *SQLiteRepository) encodeCursor(createdAt time.Time, id int64) string {
+	payload := createdAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.FormatInt(id, 10)
+	sig := r.signCursorPayload(payload)
+	return base64.URLEncoding.EncodeToString([]byte(payload)) + "." + hex.EncodeToString(sig)
+}
+
+// signCursorPayload returns payload's HMAC-SHA256 under r.cursorSecret.
+func (r *SQLiteRepository) signCursorPayload(payload string) []byte {
+	mac := hmac.New(sha256.New, r.cursorSecret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// cursorPosition is a decoded ListOptions.Cursor.
+type cursorPosition struct {
+	CreatedAt time.Time
+	ID        int64
+}
+
+func (r *SQLiteRepository) decodeCursor(cursor string) (*cursorPosition, error) {
+	payloadB64, sigHex, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor format")
+	}
+
+	wantSig, err := hex.DecodeString(sigHex)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor signature: %w", err)
+	}
+	raw, err := base64.URLEncoding.DecodeString(payloadB64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	if !hmac.Equal(wantSig, r.signCursorPayload(string(raw))) {
+		return nil, fmt.Errorf("invalid cursor signature")
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid cursor format")
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor timestamp: %w", err)
+	}
+	id, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return &cursorPosition{CreatedAt: createdAt, ID: id}, nil
+}
+
+// ListFiltered returns one page of feedback matching opts, newest first,
+// along with the cursors for the adjacent pages (empty when there isn't
+// one). It's the cursor-paginated, filterable sibling of List/Search -
+// HandleFeedbackList and HandleList both page through results this way
+// instead of List's plain OFFSET.
+func (r *SQLiteRepository) ListFiltered(opts ListOptions) (items []*model.Feedback, nextCursor, prevCursor string, err error) {
+	limit := opts.Limit
+	if limit <= 0 {
+		limit = 50
+	}
+
+	var conditions []string
+	var args []interface{}
+
+	if opts.Status != "" {
+		conditions = append(conditions, "f.status = ?")
+		args = append(args, opts.Status)
+	}
+	if opts.Priority != "" {
+		conditions = append(conditions, "f.priority = ?")
+		args = append(args, opts.Priority)
+	}
+	if opts.Category != "" {
+		conditions = append(conditions, "f.category = ?")
+		args = append(args, opts.Category)
+	}
+	if opts.Type != "" {
+		conditions = append(conditions, "f.type = ?")
+		args = append(args, opts.Type)
+	}
+	if opts.PredictedPriority != "" {
+		conditions = append(conditions, "f.predicted_priority = ?")
+		args = append(args, opts.PredictedPriority)
+	}
+	if !opts.Since.IsZero() {
+		conditions = append(conditions, "f.created_at >= ?")
+		args = append(args, opts.Since.UTC())
+	}
+	if opts.HasAnalysis != nil {
+		if *opts.HasAnalysis {
+			conditions = append(conditions, "f.analysis IS NOT NULL AND f.analysis != ''")
+		} else {
+			conditions = append(conditions, "(f.analysis IS NULL OR f.analysis = '')")
+		}
+	}
+
+	var pos *cursorPosition
+	if opts.Cursor != "" {
+		pos, err = r.decodeCursor(opts.Cursor)
+		if err != nil {
+			return nil, "", "", err
+		}
+	}
+
+	forward := opts.Direction != "prev"
+	if pos != nil {
+		if forward {
+			conditions = append(conditions, "(f.created_at < ? OR (f.created_at = ? AND f.id < ?))")
+		} else {
+			conditions = append(conditions, "(f.created_at > ? OR (f.created_at = ? AND f.id > ?))")
+		}
+		args = append(args, pos.CreatedAt, pos.CreatedAt, pos.ID)
+	}
+
+	fromFTS := opts.Query != ""
+	if fromFTS {
+		conditions = append([]string{"feedback_fts MATCH ?"}, conditions...)
+		args = append([]interface{}{opts.Query}, args...)
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	orderDir := "DESC"
+	if !forward {
+		orderDir = "ASC"
+	}
+
+	columns := `
+		f.id, f.user_email, f.user_name, f.title, f.description, f.type, f.url, f.user_agent,
+		f.screen_width, f.screen_height, f.viewport_width, f.viewport_height,
+		f.screen_resolution, f.pixel_ratio,
+		f.browser_name, f.browser_version, f.os, f.device_type, f.is_mobile,
+		f.language, f.timezone,
+		f.screenshot, f.annotations, f.console_logs, f.journey,
+		f.status, f.priority, f.category, f.effort_estimate,
+		f.analysis, f.predicted_priority, f.predicted_category, f.predicted_effort,
+		f.created_at, f.updated_at`
+
+	var query string
+	if fromFTS {
+		query = fmt.Sprintf(`
+		SELECT %s
+		FROM feedback_fts
+		JOIN feedback f ON f.id = feedback_fts.rowid
+		%s
+		ORDER BY f.created_at %s, f.id %s
+		LIMIT ?`, columns, where, orderDir, orderDir)
+	} else {
+		query = fmt.Sprintf(`
+		SELECT %s
+		FROM feedback f
+		%s
+		ORDER BY f.created_at %s, f.id %s
+		LIMIT ?`, columns, where, orderDir, orderDir)
+	}
+
+	queryArgs := append(append([]interface{}{}, args...), limit+1)
+	rows, err := r.db.Query(query, queryArgs...)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to list feedback: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		f := &model.Feedback{}
+		err := rows.Scan(
+			&f.ID, &f.UserEmail, &f.UserName,
+			&f.Title, &f.Description, &f.Type,
+			&f.URL, &f.UserAgent,
+			&f.ScreenWidth, &f.ScreenHeight,
+			&f.ViewportWidth, &f.ViewportHeight,
+			&f.ScreenResolution, &f.PixelRatio,
+			&f.BrowserName, &f.BrowserVersion,
+			&f.OS, &f.DeviceType, &f.IsMobile,
+			&f.Language, &f.Timezone,
+			&f.Screenshot, &f.Annotations,
+			&f.ConsoleLogs, &f.Journey,
+			&f.Status, &f.Priority, &f.Category, &f.EffortEstimate,
+			&f.Analysis, &f.PredictedPriority,
+			&f.PredictedCategory, &f.PredictedEffort,
+			&f.CreatedAt, &f.UpdatedAt,
+		)
+		if err != nil {
+			return nil, "", "", fmt.Errorf("failed to scan feedback: %w", err)
+		}
+		items = append(items, f)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", "", fmt.Errorf("failed to list feedback: %w", err)
+	}
+
+	hasMore := len(items) > limit
+	if hasMore {
+		items = items[:limit]
+	}
+	if !forward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	if len(items) == 0 {
+		return items, "", "", nil
+	}
+
+	first, last := items[0], items[len(items)-1]
+	if forward {
+		if hasMore {
+			nextCursor = r.encodeCursor(last.CreatedAt, last.ID)
+		}
+		if pos != nil {
+			prevCursor = r.encodeCursor(first.CreatedAt, first.ID)
+		}
+	} else {
+		if hasMore {
+			prevCursor = r.encodeCursor(first.CreatedAt, first.ID)
+		}
+		nextCursor = r.encodeCursor(last.CreatedAt, last.ID)
+	}
+
+	return items, nextCursor, prevCursor, nil
+}
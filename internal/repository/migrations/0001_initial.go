@@ -0,0 +1,85 @@
+package migrations
+
+import "database/sql"
+
+func init() {
+	Register(Migration{
+		Version: 1,
+		Name:    "initial",
+		Up:      up0001,
+		Down:    down0001,
+	})
+}
+
+// up0001 creates the original feedback table and its indexes.
+func up0001(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS feedback (
+		-- Primary Key: Auto-incrementing integer ID.
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+		-- User Identity
+		user_email TEXT,
+		user_name TEXT,
+
+		-- Core Content
+		title TEXT NOT NULL,
+		description TEXT NOT NULL,
+		type TEXT DEFAULT 'other',
+
+		-- Context
+		url TEXT,
+		user_agent TEXT,
+
+		-- Device Metrics (useful for UI debugging)
+		screen_width INTEGER,
+		screen_height INTEGER,
+		viewport_width INTEGER,
+		viewport_height INTEGER,
+		screen_resolution TEXT,
+		pixel_ratio REAL,
+
+		-- Platform Info
+		browser_name TEXT,
+		browser_version TEXT,
+		os TEXT,
+		device_type TEXT,
+		is_mobile BOOLEAN DEFAULT FALSE,
+		language TEXT,
+		timezone TEXT,
+
+		-- Large Text Blobs (JSON or Base64 content)
+		screenshot TEXT,
+		annotations TEXT,
+		console_logs TEXT,
+		journey TEXT,
+
+		-- Triage Status
+		status TEXT DEFAULT 'open',
+		priority TEXT,
+		category TEXT,
+		effort_estimate TEXT,
+
+		-- AI/LLM Analysis Results
+		analysis TEXT,
+		predicted_priority TEXT,
+		predicted_category TEXT,
+		predicted_effort TEXT,
+
+		-- Audit Timestamps
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	-- Indexes for common query patterns (Filtering by status, type, or date).
+	CREATE INDEX IF NOT EXISTS idx_feedback_status ON feedback(status);
+	CREATE INDEX IF NOT EXISTS idx_feedback_type ON feedback(type);
+	CREATE INDEX IF NOT EXISTS idx_feedback_created_at ON feedback(created_at);
+	`)
+	return err
+}
+
+func down0001(tx *sql.Tx) error {
+	_, err := tx.Exec(`DROP TABLE IF EXISTS feedback;`)
+	return err
+}
@@ -0,0 +1,55 @@
+package migrations
+
+import "database/sql"
+
+func init() {
+	Register(Migration{
+		Version: 2,
+		Name:    "errors_table",
+		Up:      up0002,
+		Down:    down0002,
+	})
+}
+
+// up0002 adds the errors table that persists every error handled by
+// handler.ErrorHandler.HandleError, so the error ID shown on the pretty page
+// can be looked up server-side.
+func up0002(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS errors (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+		error_id TEXT NOT NULL UNIQUE,
+		trace_id TEXT,
+		-- Identifies the underlying failure (vs. this specific occurrence),
+		-- so repeated incidents can be counted. See handler.Fingerprint.
+		fingerprint TEXT,
+
+		handler_file TEXT NOT NULL,
+		handler_func TEXT NOT NULL,
+		request_path TEXT,
+		request_method TEXT,
+		status_code INTEGER,
+
+		error_message TEXT,
+		stack_trace TEXT,
+		user_agent TEXT,
+
+		-- Populated once the analyzer (OpenCode/LLM) finishes or is replayed.
+		analysis TEXT,
+		resolved_at DATETIME,
+
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_errors_error_id ON errors(error_id);
+	CREATE INDEX IF NOT EXISTS idx_errors_created_at ON errors(created_at);
+	CREATE INDEX IF NOT EXISTS idx_errors_fingerprint ON errors(fingerprint);
+	`)
+	return err
+}
+
+func down0002(tx *sql.Tx) error {
+	_, err := tx.Exec(`DROP TABLE IF EXISTS errors;`)
+	return err
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import "database/sql"
+
+func init() {
+	Register(Migration{
+		Version: 3,
+		Name:    "feedback_fts",
+		Up:      up0003,
+		Down:    down0003,
+	})
+}
+
+// up0003 adds an FTS5 virtual table covering the free-text fields of
+// feedback, kept in sync via triggers on insert/update/delete so callers
+// never need to remember to update it by hand.
+func up0003(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE VIRTUAL TABLE IF NOT EXISTS feedback_fts USING fts5(
+		title, description, console_logs, analysis, journey,
+		content='feedback', content_rowid='id'
+	);
+
+	CREATE TRIGGER IF NOT EXISTS feedback_fts_ai AFTER INSERT ON feedback BEGIN
+		INSERT INTO feedback_fts(rowid, title, description, console_logs, analysis, journey)
+		VALUES (new.id, new.title, new.description, new.console_logs, new.analysis, new.journey);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS feedback_fts_ad AFTER DELETE ON feedback BEGIN
+		INSERT INTO feedback_fts(feedback_fts, rowid, title, description, console_logs, analysis, journey)
+		VALUES ('delete', old.id, old.title, old.description, old.console_logs, old.analysis, old.journey);
+	END;
+
+	CREATE TRIGGER IF NOT EXISTS feedback_fts_au AFTER UPDATE ON feedback BEGIN
+		INSERT INTO feedback_fts(feedback_fts, rowid, title, description, console_logs, analysis, journey)
+		VALUES ('delete', old.id, old.title, old.description, old.console_logs, old.analysis, old.journey);
+		INSERT INTO feedback_fts(rowid, title, description, console_logs, analysis, journey)
+		VALUES (new.id, new.title, new.description, new.console_logs, new.analysis, new.journey);
+	END;
+	`)
+	return err
+}
+
+func down0003(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	DROP TRIGGER IF EXISTS feedback_fts_au;
+	DROP TRIGGER IF EXISTS feedback_fts_ad;
+	DROP TRIGGER IF EXISTS feedback_fts_ai;
+	DROP TABLE IF EXISTS feedback_fts;
+	`)
+	return err
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import "database/sql"
+
+func init() {
+	Register(Migration{
+		Version: 4,
+		Name:    "selfhealing_jobs",
+		Up:      up0004,
+		Down:    down0004,
+	})
+}
+
+// up0004 adds the jobs table backing selfhealing.Trigger's durable queue,
+// which replaces the old in-memory isRunning gate: TriggerAsync inserts a
+// row here instead of launching a goroutine directly, so a crash mid-
+// analysis doesn't lose the work (see selfhealing.NewTrigger's startup
+// recovery pass over state='running' rows).
+func up0004(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS jobs (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+		feedback_id INTEGER NOT NULL,
+		mode TEXT NOT NULL,
+		state TEXT NOT NULL DEFAULT 'queued',
+		attempts INTEGER NOT NULL DEFAULT 0,
+		last_error TEXT,
+		request_id TEXT,
+
+		started_at DATETIME,
+		finished_at DATETIME,
+		next_attempt_at DATETIME,
+		result_json TEXT,
+
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_jobs_state ON jobs(state);
+	CREATE INDEX IF NOT EXISTS idx_jobs_feedback_id ON jobs(feedback_id);
+	`)
+	return err
+}
+
+func down0004(tx *sql.Tx) error {
+	_, err := tx.Exec(`DROP TABLE IF EXISTS jobs;`)
+	return err
+}
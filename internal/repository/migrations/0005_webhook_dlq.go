@@ -0,0 +1,42 @@
+package migrations
+
+import "database/sql"
+
+func init() {
+	Register(Migration{
+		Version: 5,
+		Name:    "webhook_dlq",
+		Up:      up0005,
+		Down:    down0005,
+	})
+}
+
+// up0005 adds the feedback_webhook_dlq table backing
+// handler.WebhookAnalyzer's DeadLetterSink: payloads that exhaust their
+// retry budget are persisted here instead of silently dropped, so an
+// operator can inspect or replay them via the /admin/webhook-dlq endpoints.
+func up0005(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS feedback_webhook_dlq (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+
+		error_id TEXT NOT NULL,
+		webhook_url TEXT NOT NULL,
+		payload_json TEXT NOT NULL,
+		last_error TEXT,
+		attempts INTEGER NOT NULL DEFAULT 0,
+
+		replayed_at DATETIME,
+		created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_webhook_dlq_error_id ON feedback_webhook_dlq(error_id);
+	CREATE INDEX IF NOT EXISTS idx_webhook_dlq_created_at ON feedback_webhook_dlq(created_at);
+	`)
+	return err
+}
+
+func down0005(tx *sql.Tx) error {
+	_, err := tx.Exec(`DROP TABLE IF EXISTS feedback_webhook_dlq;`)
+	return err
+}
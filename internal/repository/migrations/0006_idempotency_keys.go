@@ -0,0 +1,38 @@
+package migrations
+
+import "database/sql"
+
+func init() {
+	Register(Migration{
+		Version: 6,
+		Name:    "idempotency_keys",
+		Up:      up0006,
+		Down:    down0006,
+	})
+}
+
+// up0006 adds the idempotency_keys table backing HandleSubmit's
+// Idempotency-Key support: a client-supplied key is reserved here before
+// the request is processed, so a retried POST (flaky mobile networks love
+// to double-send) can return the original response instead of creating a
+// duplicate feedback row and re-running a duplicate (and costly) LLM
+// self-healing pass.
+func up0006(tx *sql.Tx) error {
+	_, err := tx.Exec(`
+	CREATE TABLE IF NOT EXISTS idempotency_keys (
+		key             TEXT PRIMARY KEY,
+		body_hash       TEXT NOT NULL,
+		response_status INTEGER NOT NULL DEFAULT 0,
+		response_body   TEXT NOT NULL DEFAULT '',
+		created_at      DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+	`)
+	return err
+}
+
+func down0006(tx *sql.Tx) error {
+	_, err := tx.Exec(`DROP TABLE IF EXISTS idempotency_keys;`)
+	return err
+}
@@ -0,0 +1,38 @@
+// Package migrations implements a lightweight schema migration framework
+// for the SQLite repository, modeled on Goose/golang-migrate: each numbered
+// migration registers an Up and a Down func, and a schema_migrations table
+// tracks which versions have been applied.
+//
+// Unlike the ad-hoc `CREATE TABLE IF NOT EXISTS` this replaces, migrations
+// can evolve columns (ALTER TABLE, backfills) and can be rolled back.
+package migrations
+
+import (
+	"database/sql"
+	"sort"
+)
+
+// Migration is a single numbered schema change.
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(*sql.Tx) error
+	Down    func(*sql.Tx) error
+}
+
+// registry holds every migration registered via Register. Each
+// NNNN_name.go file calls Register from its init().
+var registry []Migration
+
+// Register adds a migration to the registry.
+func Register(m Migration) {
+	registry = append(registry, m)
+}
+
+// All returns every registered migration, sorted by version ascending.
+func All() []Migration {
+	sorted := make([]Migration, len(registry))
+	copy(sorted, registry)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return sorted
+}
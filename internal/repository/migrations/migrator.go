@@ -0,0 +1,160 @@
+package migrations
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// schemaMigrationsTable records which migration versions have been applied.
+// Created lazily so a brand new database starts from a clean slate.
+const schemaMigrationsTable = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    INTEGER PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);`
+
+// Migrator applies and reverts registered migrations against a database.
+type Migrator struct {
+	db *sql.DB
+}
+
+// NewMigrator creates a Migrator bound to db.
+func NewMigrator(db *sql.DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Status describes whether a given migration has been applied.
+type Status struct {
+	Version int
+	Name    string
+	Applied bool
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.db.Exec(schemaMigrationsTable)
+	return err
+}
+
+// appliedVersions returns the set of migration versions already recorded in
+// schema_migrations.
+func (m *Migrator) appliedVersions() (map[int]bool, error) {
+	rows, err := m.db.Query(`SELECT version FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// Up applies every pending migration in order, each inside its own
+// transaction so a failing migration never leaves a half-applied schema.
+func (m *Migrator) Up() error {
+	if err := m.ensureTable(); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	for _, mig := range All() {
+		if applied[mig.Version] {
+			continue
+		}
+
+		tx, err := m.db.Begin()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction for migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+
+		if err := mig.Up(tx); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %04d_%s failed: %w", mig.Version, mig.Name, err)
+		}
+
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`, mig.Version, mig.Name); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to record migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit migration %04d_%s: %w", mig.Version, mig.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down() error {
+	if err := m.ensureTable(); err != nil {
+		return fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	all := All()
+	var target *Migration
+	for i := len(all) - 1; i >= 0; i-- {
+		if applied[all[i].Version] {
+			target = &all[i]
+			break
+		}
+	}
+	if target == nil {
+		return nil // nothing to revert
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction for migration %04d_%s: %w", target.Version, target.Name, err)
+	}
+
+	if err := target.Down(tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %04d_%s rollback failed: %w", target.Version, target.Name, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, target.Version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to unrecord migration %04d_%s: %w", target.Version, target.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// Status reports the applied/pending state of every registered migration.
+func (m *Migrator) Status() ([]Status, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations table: %w", err)
+	}
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+
+	var statuses []Status
+	for _, mig := range All() {
+		statuses = append(statuses, Status{
+			Version: mig.Version,
+			Name:    mig.Name,
+			Applied: applied[mig.Version],
+		})
+	}
+	return statuses, nil
+}
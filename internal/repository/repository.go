@@ -12,11 +12,15 @@
 package repository
 
 import (
+	"crypto/rand"
 	"database/sql"
 	"fmt"
+	"log"
+	"os"
 	"time"
 
 	"github.com/bluefermion/feedback/internal/model"
+	"github.com/bluefermion/feedback/internal/repository/migrations"
 	// We use the modernc.org/sqlite driver because it's a pure Go implementation (no CGO required).
 	// This makes cross-compilation and deployment (e.g., in minimal Docker containers) extremely easy.
 	_ "modernc.org/sqlite"
@@ -26,6 +30,10 @@ import (
 // It implements the interface expected by the handlers (though we use struct directly here for simplicity).
 type SQLiteRepository struct {
 	db *sql.DB
+	// cursorSecret signs ListFiltered's pagination cursors (see
+	// list_options.go) so a client can't forge or alter one to seek to an
+	// arbitrary position.
+	cursorSecret []byte
 }
 
 // NewSQLiteRepository initializes the database connection and ensures the schema exists.
@@ -44,87 +52,41 @@ func NewSQLiteRepository(dbPath string) (*SQLiteRepository, error) {
 		return nil, fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 
-	repo := &SQLiteRepository{db: db}
+	repo := &SQLiteRepository{db: db, cursorSecret: cursorSecretFromEnv()}
 
-	// Auto-migration on startup.
-	// For small projects/demos, checking/creating tables on startup is convenient.
-	// For large production systems, use dedicated migration tools (like golang-migrate or Goose).
-	if err := repo.migrate(); err != nil {
+	// Apply any pending migrations on startup. Each migration is a numbered
+	// Go file under internal/repository/migrations registering an Up/Down
+	// pair; schema_migrations tracks which versions have been applied so
+	// this is safe to run on every boot. Operators can also apply/inspect
+	// migrations independently of process start via `feedback migrate`.
+	if err := migrations.NewMigrator(db).Up(); err != nil {
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
 
+	go repo.sweepIdempotencyKeysLoop()
+
 	return repo, nil
 }
 
-// migrate handles schema evolution. It runs SQL DDL statements to ensure tables exist.
-func (r *SQLiteRepository) migrate() error {
-	// We define the full schema here.
-	// SQLite's "IF NOT EXISTS" clause is idempotent, allowing this to run safely on every boot.
-	query := `
-	CREATE TABLE IF NOT EXISTS feedback (
-		-- Primary Key: Auto-incrementing integer ID.
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-
-		-- User Identity
-		user_email TEXT,
-		user_name TEXT,
-
-		-- Core Content
-		title TEXT NOT NULL,
-		description TEXT NOT NULL,
-		type TEXT DEFAULT 'other',
-
-		-- Context
-		url TEXT,
-		user_agent TEXT,
-
-		-- Device Metrics (useful for UI debugging)
-		screen_width INTEGER,
-		screen_height INTEGER,
-		viewport_width INTEGER,
-		viewport_height INTEGER,
-		screen_resolution TEXT,
-		pixel_ratio REAL,
-
-		-- Platform Info
-		browser_name TEXT,
-		browser_version TEXT,
-		os TEXT,
-		device_type TEXT,
-		is_mobile BOOLEAN DEFAULT FALSE,
-		language TEXT,
-		timezone TEXT,
-
-		-- Large Text Blobs (JSON or Base64 content)
-		screenshot TEXT,
-		annotations TEXT,
-		console_logs TEXT,
-		journey TEXT,
-
-		-- Triage Status
-		status TEXT DEFAULT 'open',
-		priority TEXT,
-		category TEXT,
-		effort_estimate TEXT,
-
-		-- AI/LLM Analysis Results
-		analysis TEXT,
-		predicted_priority TEXT,
-		predicted_category TEXT,
-		predicted_effort TEXT,
-
-		-- Audit Timestamps
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	-- Indexes for common query patterns (Filtering by status, type, or date).
-	CREATE INDEX IF NOT EXISTS idx_feedback_status ON feedback(status);
-	CREATE INDEX IF NOT EXISTS idx_feedback_type ON feedback(type);
-	CREATE INDEX IF NOT EXISTS idx_feedback_created_at ON feedback(created_at);
-	`
-	_, err := r.db.Exec(query)
-	return err
+// cursorSecretFromEnv loads the HMAC key ListFiltered's cursors are signed
+// with from FEEDBACK_CURSOR_SECRET. If unset, it falls back to a random
+// per-process secret: cursors are still tamper-proof, but a client's cursor
+// stops validating across a restart (it'll get an "invalid cursor" error
+// and can simply start paging over from the beginning), so operators that
+// want cursors to survive a restart should set the env var.
+func cursorSecretFromEnv() []byte {
+	if secret := os.Getenv("FEEDBACK_CURSOR_SECRET"); secret != "" {
+		return []byte(secret)
+	}
+
+	log.Printf("FEEDBACK_CURSOR_SECRET not set, generating a random per-process cursor signing key " +
+		"(pagination cursors won't survive a restart)")
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		log.Printf("failed to generate random cursor secret, falling back to a fixed (less secure) key: %v", err)
+		return []byte("feedback-default-cursor-secret")
+	}
+	return secret
 }
 
 // Create inserts a new feedback record into the database.
@@ -317,6 +279,416 @@ func (r *SQLiteRepository) UpdateAnalysis(id int64, analysis string) error {
 	return nil
 }
 
+// CreateError persists a newly captured error record.
+func (r *SQLiteRepository) CreateError(e *model.StoredError) (int64, error) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now().UTC()
+	}
+
+	query := `
+	INSERT INTO errors (
+		error_id, trace_id, fingerprint, handler_file, handler_func, request_path, request_method,
+		status_code, error_message, stack_trace, user_agent, created_at
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query,
+		e.ErrorID, e.TraceID, e.Fingerprint, e.HandlerFile, e.HandlerFunc, e.RequestPath, e.RequestMethod,
+		e.StatusCode, e.ErrorMessage, e.StackTrace, e.UserAgent, e.CreatedAt,
+	)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert error: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get insert ID: %w", err)
+	}
+
+	e.ID = id
+	return id, nil
+}
+
+// GetErrorByID retrieves a single error record by its public error_id
+// (the ID shown on the pretty error page), not the internal auto-increment ID.
+func (r *SQLiteRepository) GetErrorByID(errorID string) (*model.StoredError, error) {
+	query := `
+	SELECT
+		id, error_id, trace_id, fingerprint, handler_file, handler_func, request_path, request_method,
+		status_code, error_message, stack_trace, user_agent, analysis, resolved_at, created_at
+	FROM errors WHERE error_id = ?`
+
+	e := &model.StoredError{}
+	err := r.db.QueryRow(query, errorID).Scan(
+		&e.ID, &e.ErrorID, &e.TraceID, &e.Fingerprint, &e.HandlerFile, &e.HandlerFunc, &e.RequestPath, &e.RequestMethod,
+		&e.StatusCode, &e.ErrorMessage, &e.StackTrace, &e.UserAgent, &e.Analysis, &e.ResolvedAt, &e.CreatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get error: %w", err)
+	}
+
+	return e, nil
+}
+
+// ListErrors returns a paginated list of captured errors, newest first.
+func (r *SQLiteRepository) ListErrors(limit, offset int) ([]*model.StoredError, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `
+	SELECT
+		id, error_id, trace_id, fingerprint, handler_file, handler_func, request_path, request_method,
+		status_code, error_message, stack_trace, user_agent, analysis, resolved_at, created_at
+	FROM errors
+	ORDER BY created_at DESC
+	LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list errors: %w", err)
+	}
+	defer rows.Close()
+
+	var errs []*model.StoredError
+	for rows.Next() {
+		e := &model.StoredError{}
+		err := rows.Scan(
+			&e.ID, &e.ErrorID, &e.TraceID, &e.Fingerprint, &e.HandlerFile, &e.HandlerFunc, &e.RequestPath, &e.RequestMethod,
+			&e.StatusCode, &e.ErrorMessage, &e.StackTrace, &e.UserAgent, &e.Analysis, &e.ResolvedAt, &e.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan error: %w", err)
+		}
+		errs = append(errs, e)
+	}
+
+	return errs, rows.Err()
+}
+
+// UpdateErrorAnalysis saves the analyzer's result for a previously captured error.
+func (r *SQLiteRepository) UpdateErrorAnalysis(errorID, analysis string) error {
+	query := `UPDATE errors SET analysis = ? WHERE error_id = ?`
+	_, err := r.db.Exec(query, analysis, errorID)
+	if err != nil {
+		return fmt.Errorf("failed to update error analysis: %w", err)
+	}
+	return nil
+}
+
+// MarkResolved stamps an error record as resolved.
+func (r *SQLiteRepository) MarkResolved(errorID string) error {
+	query := `UPDATE errors SET resolved_at = ? WHERE error_id = ?`
+	_, err := r.db.Exec(query, time.Now().UTC(), errorID)
+	if err != nil {
+		return fmt.Errorf("failed to mark error resolved: %w", err)
+	}
+	return nil
+}
+
+// CreateJob persists a newly queued self-healing job.
+func (r *SQLiteRepository) CreateJob(job *model.Job) (int64, error) {
+	if job.State == "" {
+		job.State = model.JobStateQueued
+	}
+	if job.CreatedAt.IsZero() {
+		job.CreatedAt = time.Now().UTC()
+	}
+
+	query := `
+	INSERT INTO jobs (feedback_id, mode, state, request_id, created_at)
+	VALUES (?, ?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query, job.FeedbackID, job.Mode, job.State, job.RequestID, job.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert job: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get insert ID: %w", err)
+	}
+
+	job.ID = id
+	return id, nil
+}
+
+// jobColumns lists the columns, in scan order, shared by every query that
+// reads back full Job rows (GetJobByID, ListJobs, ClaimNextJob).
+const jobColumns = `
+	id, feedback_id, mode, state, attempts, last_error, request_id,
+	started_at, finished_at, next_attempt_at, result_json, created_at`
+
+// scanJob scans a single jobs row via either *sql.Row.Scan or
+// *sql.Rows.Scan (both satisfy this signature), converting the table's
+// nullable columns into Job's pointer/zero-value fields.
+func scanJob(scan func(dest ...interface{}) error) (*model.Job, error) {
+	var j model.Job
+	var lastError, requestID, resultJSON sql.NullString
+	var startedAt, finishedAt, nextAttemptAt sql.NullTime
+
+	if err := scan(
+		&j.ID, &j.FeedbackID, &j.Mode, &j.State, &j.Attempts,
+		&lastError, &requestID, &startedAt, &finishedAt, &nextAttemptAt,
+		&resultJSON, &j.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	j.LastError = lastError.String
+	j.RequestID = requestID.String
+	j.ResultJSON = resultJSON.String
+	if startedAt.Valid {
+		j.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		j.FinishedAt = &finishedAt.Time
+	}
+	if nextAttemptAt.Valid {
+		j.NextAttemptAt = &nextAttemptAt.Time
+	}
+
+	return &j, nil
+}
+
+// ClaimNextJob atomically claims the oldest eligible queued job (state =
+// 'queued' and due by next_attempt_at), incrementing its attempt counter and
+// marking it running. Returns (nil, nil) if no job is ready to claim.
+//
+// Claiming is a select-then-update rather than a single statement because
+// SQLite can't express "atomically pick and update the oldest matching row"
+// in one step; the WHERE id = ? AND state = 'queued' guard on the UPDATE
+// detects a concurrent worker that claimed the same row first, in which
+// case we fall through to the next candidate instead of double-running it.
+func (r *SQLiteRepository) ClaimNextJob() (*model.Job, error) {
+	for attempt := 0; attempt < 5; attempt++ {
+		var id int64
+		err := r.db.QueryRow(`
+		SELECT id FROM jobs
+		WHERE state = 'queued' AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+		ORDER BY created_at ASC
+		LIMIT 1`, time.Now().UTC()).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to find queued job: %w", err)
+		}
+
+		result, err := r.db.Exec(`
+		UPDATE jobs SET state = 'running', attempts = attempts + 1, started_at = ?
+		WHERE id = ? AND state = 'queued'`, time.Now().UTC(), id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to claim job %d: %w", id, err)
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check claim result for job %d: %w", id, err)
+		}
+		if affected == 0 {
+			// Lost the race to another worker; try the next candidate.
+			continue
+		}
+
+		return r.GetJobByID(id)
+	}
+	return nil, nil
+}
+
+// GetJobByID retrieves a single job by its internal ID.
+func (r *SQLiteRepository) GetJobByID(id int64) (*model.Job, error) {
+	query := `SELECT` + jobColumns + ` FROM jobs WHERE id = ?`
+
+	job, err := scanJob(r.db.QueryRow(query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get job: %w", err)
+	}
+	return job, nil
+}
+
+// ListJobs returns a paginated list of self-healing jobs, newest first.
+func (r *SQLiteRepository) ListJobs(limit, offset int) ([]*model.Job, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `SELECT` + jobColumns + ` FROM jobs ORDER BY created_at DESC LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*model.Job
+	for rows.Next() {
+		job, err := scanJob(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// FinishJob records a terminal outcome (done or dead) for a job, persisting
+// the serialized selfhealing.Result so /api/selfhealing/jobs/{id} can show
+// it after the fact.
+func (r *SQLiteRepository) FinishJob(id int64, state model.JobState, resultJSON, lastError string) error {
+	query := `
+	UPDATE jobs SET state = ?, result_json = ?, last_error = ?, finished_at = ?
+	WHERE id = ?`
+	_, err := r.db.Exec(query, state, resultJSON, lastError, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to finish job %d: %w", id, err)
+	}
+	return nil
+}
+
+// RequeueJob sends a job back to the queue for a retry after nextAttemptAt
+// (exponential backoff), recording the error that triggered the retry. The
+// attempt counter itself was already incremented by ClaimNextJob.
+func (r *SQLiteRepository) RequeueJob(id int64, nextAttemptAt time.Time, lastError string) error {
+	query := `
+	UPDATE jobs SET state = 'queued', next_attempt_at = ?, last_error = ?, started_at = NULL
+	WHERE id = ?`
+	_, err := r.db.Exec(query, nextAttemptAt, lastError, id)
+	if err != nil {
+		return fmt.Errorf("failed to requeue job %d: %w", id, err)
+	}
+	return nil
+}
+
+// ResetRunningJobs requeues any job left in state='running' by a crash (the
+// process died mid-analysis without reaching FinishJob/RequeueJob), with its
+// attempt counter incremented same as a normal retry, so no feedback
+// silently loses its self-healing pass. Called once at startup by
+// selfhealing.NewTrigger, before any worker starts claiming jobs. Returns
+// the number of jobs reset.
+func (r *SQLiteRepository) ResetRunningJobs() (int, error) {
+	result, err := r.db.Exec(`
+	UPDATE jobs SET state = 'queued', attempts = attempts + 1, next_attempt_at = NULL
+	WHERE state = 'running'`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reset running jobs: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to count reset jobs: %w", err)
+	}
+	return int(affected), nil
+}
+
+// CreateWebhookDeadLetter persists a webhook payload that exhausted its
+// retry budget (see handler.WebhookAnalyzer), so it can be inspected or
+// replayed via the /admin/webhook-dlq endpoints instead of being lost.
+func (r *SQLiteRepository) CreateWebhookDeadLetter(dl *model.WebhookDeadLetter) (int64, error) {
+	if dl.CreatedAt.IsZero() {
+		dl.CreatedAt = time.Now().UTC()
+	}
+
+	query := `
+	INSERT INTO feedback_webhook_dlq (error_id, webhook_url, payload_json, last_error, attempts, created_at)
+	VALUES (?, ?, ?, ?, ?, ?)`
+
+	result, err := r.db.Exec(query, dl.ErrorID, dl.WebhookURL, dl.PayloadJSON, dl.LastError, dl.Attempts, dl.CreatedAt)
+	if err != nil {
+		return 0, fmt.Errorf("failed to insert webhook dead letter: %w", err)
+	}
+
+	id, err := result.LastInsertId()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get insert ID: %w", err)
+	}
+
+	dl.ID = id
+	return id, nil
+}
+
+// webhookDeadLetterColumns lists the columns, in scan order, shared by every
+// query that reads back full feedback_webhook_dlq rows.
+const webhookDeadLetterColumns = `
+	id, error_id, webhook_url, payload_json, last_error, attempts, replayed_at, created_at`
+
+// scanWebhookDeadLetter scans a single feedback_webhook_dlq row via either
+// *sql.Row.Scan or *sql.Rows.Scan.
+func scanWebhookDeadLetter(scan func(dest ...interface{}) error) (*model.WebhookDeadLetter, error) {
+	var dl model.WebhookDeadLetter
+	var replayedAt sql.NullTime
+
+	if err := scan(
+		&dl.ID, &dl.ErrorID, &dl.WebhookURL, &dl.PayloadJSON, &dl.LastError, &dl.Attempts,
+		&replayedAt, &dl.CreatedAt,
+	); err != nil {
+		return nil, err
+	}
+
+	if replayedAt.Valid {
+		dl.ReplayedAt = &replayedAt.Time
+	}
+
+	return &dl, nil
+}
+
+// GetWebhookDeadLetterByID retrieves a single dead-lettered webhook payload.
+func (r *SQLiteRepository) GetWebhookDeadLetterByID(id int64) (*model.WebhookDeadLetter, error) {
+	query := `SELECT` + webhookDeadLetterColumns + ` FROM feedback_webhook_dlq WHERE id = ?`
+
+	dl, err := scanWebhookDeadLetter(r.db.QueryRow(query, id).Scan)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook dead letter: %w", err)
+	}
+	return dl, nil
+}
+
+// ListWebhookDeadLetters returns a paginated list of dead-lettered webhook
+// payloads, newest first.
+func (r *SQLiteRepository) ListWebhookDeadLetters(limit, offset int) ([]*model.WebhookDeadLetter, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	query := `SELECT` + webhookDeadLetterColumns + ` FROM feedback_webhook_dlq ORDER BY created_at DESC LIMIT ? OFFSET ?`
+
+	rows, err := r.db.Query(query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list webhook dead letters: %w", err)
+	}
+	defer rows.Close()
+
+	var dls []*model.WebhookDeadLetter
+	for rows.Next() {
+		dl, err := scanWebhookDeadLetter(rows.Scan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan webhook dead letter: %w", err)
+		}
+		dls = append(dls, dl)
+	}
+
+	return dls, rows.Err()
+}
+
+// MarkWebhookDeadLetterReplayed stamps a dead-lettered payload as replayed,
+// so the admin UI can distinguish already-handled entries from new ones.
+func (r *SQLiteRepository) MarkWebhookDeadLetterReplayed(id int64) error {
+	query := `UPDATE feedback_webhook_dlq SET replayed_at = ? WHERE id = ?`
+	_, err := r.db.Exec(query, time.Now().UTC(), id)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook dead letter %d replayed: %w", id, err)
+	}
+	return nil
+}
+
 // Close terminates the database connection.
 func (r *SQLiteRepository) Close() error {
 	return r.db.Close()
@@ -0,0 +1,157 @@
+// Package repository implements the data persistence layer using SQLite.
+//
+// search.go adds full-text search over feedback via the feedback_fts FTS5
+// virtual table (see migrations/0003_feedback_fts.go).
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// SearchFilters narrows a Search query using the same triage fields exposed
+// elsewhere in the API. Zero-valued fields are not applied.
+type SearchFilters struct {
+	Status            string
+	Type              string
+	Priority          string
+	Category          string
+	PredictedPriority string
+	IsMobile          *bool
+	CreatedAfter      time.Time
+	CreatedBefore     time.Time
+}
+
+// SearchResult pairs a matched feedback record with a highlighted snippet
+// of the text that matched.
+type SearchResult struct {
+	Feedback *model.Feedback `json:"feedback"`
+	Snippet  string          `json:"snippet"`
+}
+
+// Search runs a full-text query against feedback_fts, applying the given
+// filters, and returns the matching page along with the total match count
+// (ignoring limit/offset) so callers can paginate.
+func (r *SQLiteRepository) Search(query string, filters SearchFilters, limit, offset int) ([]*SearchResult, int, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+
+	conditions := []string{"feedback_fts MATCH ?"}
+	args := []interface{}{query}
+
+	if filters.Status != "" {
+		conditions = append(conditions, "f.status = ?")
+		args = append(args, filters.Status)
+	}
+	if filters.Type != "" {
+		conditions = append(conditions, "f.type = ?")
+		args = append(args, filters.Type)
+	}
+	if filters.Priority != "" {
+		conditions = append(conditions, "f.priority = ?")
+		args = append(args, filters.Priority)
+	}
+	if filters.Category != "" {
+		conditions = append(conditions, "f.category = ?")
+		args = append(args, filters.Category)
+	}
+	if filters.PredictedPriority != "" {
+		conditions = append(conditions, "f.predicted_priority = ?")
+		args = append(args, filters.PredictedPriority)
+	}
+	if filters.IsMobile != nil {
+		conditions = append(conditions, "f.is_mobile = ?")
+		args = append(args, *filters.IsMobile)
+	}
+	if !filters.CreatedAfter.IsZero() {
+		conditions = append(conditions, "f.created_at >= ?")
+		args = append(args, filters.CreatedAfter)
+	}
+	if !filters.CreatedBefore.IsZero() {
+		conditions = append(conditions, "f.created_at <= ?")
+		args = append(args, filters.CreatedBefore)
+	}
+
+	where := strings.Join(conditions, " AND ")
+
+	countQuery := fmt.Sprintf(`
+	SELECT COUNT(*)
+	FROM feedback_fts
+	JOIN feedback f ON f.id = feedback_fts.rowid
+	WHERE %s`, where)
+
+	var total int
+	if err := r.db.QueryRow(countQuery, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+
+	selectQuery := fmt.Sprintf(`
+	SELECT
+		f.id, f.user_email, f.user_name, f.title, f.description, f.type, f.url, f.user_agent,
+		f.screen_width, f.screen_height, f.viewport_width, f.viewport_height,
+		f.screen_resolution, f.pixel_ratio,
+		f.browser_name, f.browser_version, f.os, f.device_type, f.is_mobile,
+		f.language, f.timezone,
+		f.screenshot, f.annotations, f.console_logs, f.journey,
+		f.status, f.priority, f.category, f.effort_estimate,
+		f.analysis, f.predicted_priority, f.predicted_category, f.predicted_effort,
+		f.created_at, f.updated_at,
+		snippet(feedback_fts, 1, '<mark>', '</mark>', '...', 12)
+	FROM feedback_fts
+	JOIN feedback f ON f.id = feedback_fts.rowid
+	WHERE %s
+	ORDER BY rank
+	LIMIT ? OFFSET ?`, where)
+
+	queryArgs := append(append([]interface{}{}, args...), limit, offset)
+	rows, err := r.db.Query(selectQuery, queryArgs...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search feedback: %w", err)
+	}
+	defer rows.Close()
+
+	var results []*SearchResult
+	for rows.Next() {
+		f := &model.Feedback{}
+		var snippet string
+		err := rows.Scan(
+			&f.ID, &f.UserEmail, &f.UserName,
+			&f.Title, &f.Description, &f.Type,
+			&f.URL, &f.UserAgent,
+			&f.ScreenWidth, &f.ScreenHeight,
+			&f.ViewportWidth, &f.ViewportHeight,
+			&f.ScreenResolution, &f.PixelRatio,
+			&f.BrowserName, &f.BrowserVersion,
+			&f.OS, &f.DeviceType, &f.IsMobile,
+			&f.Language, &f.Timezone,
+			&f.Screenshot, &f.Annotations,
+			&f.ConsoleLogs, &f.Journey,
+			&f.Status, &f.Priority, &f.Category, &f.EffortEstimate,
+			&f.Analysis, &f.PredictedPriority,
+			&f.PredictedCategory, &f.PredictedEffort,
+			&f.CreatedAt, &f.UpdatedAt,
+			&snippet,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan search result: %w", err)
+		}
+		results = append(results, &SearchResult{Feedback: f, Snippet: snippet})
+	}
+
+	return results, total, rows.Err()
+}
+
+// RebuildFTS repopulates feedback_fts from the feedback table. Needed if
+// the FTS index/triggers are ever added to a database that already has
+// rows (the triggers only keep it in sync going forward).
+func (r *SQLiteRepository) RebuildFTS() error {
+	_, err := r.db.Exec(`INSERT INTO feedback_fts(feedback_fts) VALUES ('rebuild')`)
+	if err != nil {
+		return fmt.Errorf("failed to rebuild FTS index: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+// Package requestid threads a per-HTTP-request correlation ID through
+// context.Context.
+//
+// It exists as its own small package, instead of living next to the
+// middleware that assigns it, because more than one side of the
+// request/response boundary needs to read or write it without creating an
+// import cycle: cmd/server's requestIDMiddleware sets it, internal/handler
+// reads it back to report errors and hand it to background jobs, and
+// internal/selfhealing reads it again to stamp the Result a job produces.
+// A shared leaf package with no dependencies of its own is the only way all
+// three can use the same context key.
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+type contextKey struct{}
+
+// New generates a fresh request ID.
+func New() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// WithValue returns a copy of ctx carrying id.
+func WithValue(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request ID stashed in ctx by WithValue, or "" if
+// none was set.
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -12,40 +12,57 @@
 package selfhealing
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
+	"math/rand"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/bluefermion/feedback/internal/model"
 )
 
 // LLMAnalyzer manages the conversation state and tool execution loop.
 type LLMAnalyzer struct {
-	apiKey     string
-	baseURL    string
-	model      string
-	sourceDir  string // Security boundary: The agent can only read files inside this root.
-	httpClient *http.Client
+	// providers is tried in order on every call: callLLM retries a
+	// retryable error on the current provider a few times and fails over to
+	// the next one once those retries run out, or at once on any other
+	// error. See provider.go.
+	providers []Provider
+	sourceDir string // Security boundary: The agent can only read files inside this root.
+	// index backs the search_code tool. Nil disables the tool so agents
+	// built via NewLLMAnalyzer keep working without an embeddings model.
+	index *CodeIndex
+	// Budget caps token/dollar spend for Analyze/AnalyzeStream calls. Nil
+	// (the default) means unlimited; set it directly after construction to
+	// opt in, e.g. `analyzer.Budget = &Budget{MaxInputTokens: 100000}`.
+	Budget *Budget
 }
 
-// NewLLMAnalyzer initializes the analyzer with credentials and configuration.
-func NewLLMAnalyzer(apiKey, baseURL, model, sourceDir string) *LLMAnalyzer {
+// NewLLMAnalyzer initializes the analyzer with an ordered list of chat
+// providers (the first handles requests; later ones are fallbacks, tried when
+// the one before fails) and the source directory tools operate within.
+func NewLLMAnalyzer(providers []Provider, sourceDir string) *LLMAnalyzer {
 	return &LLMAnalyzer{
-		apiKey:     apiKey,
-		baseURL:    baseURL,
-		model:      model,
-		sourceDir:  sourceDir,
-		httpClient: &http.Client{}, // Default client. In prod, set timeouts here!
+		providers: providers,
+		sourceDir: sourceDir,
 	}
 }
 
+// NewLLMAnalyzerWithIndex is like NewLLMAnalyzer but additionally equips the
+// agent with the search_code tool, backed by a CodeIndex over sourceDir
+// using its own embeddings credentials (usually a separate, cheaper API
+// from the chat providers) and returning the top k nearest chunks per query.
+func NewLLMAnalyzerWithIndex(providers []Provider, sourceDir, embeddingAPIKey, embeddingBaseURL, embeddingModel string, k int) *LLMAnalyzer {
+	a := NewLLMAnalyzer(providers, sourceDir)
+	a.index = NewCodeIndex(sourceDir, embeddingAPIKey, embeddingBaseURL, embeddingModel, k)
+	return a
+}
+
 // -----------------------------------------------------------------------------
 // LLM API TYPES (OpenAI-compatible Schema)
 // -----------------------------------------------------------------------------
@@ -89,6 +106,7 @@ type ChatRequest struct {
 	Tools       []Tool        `json:"tools,omitempty"`
 	MaxTokens   int           `json:"max_tokens,omitempty"`
 	Temperature float64       `json:"temperature,omitempty"` // Lower (0.2) = more deterministic/factual
+	Stream      bool          `json:"stream,omitempty"`      // Requests an SSE response; see callLLMStream.
 }
 
 // ChatResponse parses the API response.
@@ -107,15 +125,159 @@ type ChatResponse struct {
 // CORE LOGIC
 // -----------------------------------------------------------------------------
 
-// Analyze orchestrates the "Thinking Loop" (ReAct Pattern).
-// 1. Prepare Prompt.
-// 2. Loop:
-//    a. Send history to LLM.
-//    b. Did LLM ask for a tool?
-//       Yes -> Execute tool -> Add result to history -> Continue.
-//       No  -> Return final answer.
-func (a *LLMAnalyzer) Analyze(ctx context.Context, feedback *model.Feedback) (string, error) {
-	// SYSTEM PROMPT: Sets the persona and operational constraints.
+// AnalyzeEventKind enumerates the stages AnalyzeStream reports as they
+// happen, so a caller (e.g. a future streaming UI) can render progress
+// instead of waiting on the whole analysis.
+type AnalyzeEventKind string
+
+const (
+	EventTokenDelta      AnalyzeEventKind = "token_delta"
+	EventToolCallDecided AnalyzeEventKind = "tool_call_decided"
+	EventToolCallStarted AnalyzeEventKind = "tool_call_started"
+	EventToolCallResult  AnalyzeEventKind = "tool_call_result"
+	EventFinalAnswer     AnalyzeEventKind = "final_answer"
+	EventError           AnalyzeEventKind = "error"
+)
+
+// AnalyzeEvent is one incremental update emitted by AnalyzeStream.
+type AnalyzeEvent struct {
+	Kind AnalyzeEventKind
+	// Content holds the token text for EventTokenDelta or the full answer
+	// for EventFinalAnswer.
+	Content string
+	// Tool is populated for the tool_call_* event kinds.
+	Tool *ToolCall
+	// Result is the tool's output, populated for EventToolCallResult.
+	Result string
+	// Err is populated for EventError.
+	Err error
+	// Usage is populated on terminal events (EventFinalAnswer, EventError)
+	// with the cumulative token/dollar spend for the whole Analyze call.
+	Usage *Usage
+}
+
+// Analyze orchestrates the "Thinking Loop" (ReAct Pattern) and returns once
+// it's done, along with the token/dollar Usage the call consumed so callers
+// can record per-feedback cost. It's implemented on top of AnalyzeStream so
+// the tool-execution loop only exists in one place; callers that want
+// progress as it happens should use AnalyzeStream directly instead.
+func (a *LLMAnalyzer) Analyze(ctx context.Context, feedback *model.Feedback) (string, Usage, error) {
+	events, err := a.AnalyzeStream(ctx, feedback)
+	if err != nil {
+		return "", Usage{}, err
+	}
+
+	var finalAnswer string
+	var lastErr error
+	var usage Usage
+	for ev := range events {
+		switch ev.Kind {
+		case EventFinalAnswer:
+			finalAnswer = ev.Content
+		case EventError:
+			lastErr = ev.Err
+		}
+		if ev.Usage != nil {
+			usage = *ev.Usage
+		}
+	}
+
+	if lastErr != nil {
+		return "", usage, lastErr
+	}
+	return finalAnswer, usage, nil
+}
+
+// AnalyzeStream runs the same ReAct loop as Analyze but returns a channel of
+// incremental events (token deltas, tool-call lifecycle, final answer)
+// instead of blocking until the whole analysis completes. The channel is
+// closed once the analysis finishes; a failure is sent as a final
+// EventError before closing rather than returned directly, since by the
+// time most failures happen the channel has already been handed back.
+func (a *LLMAnalyzer) AnalyzeStream(ctx context.Context, feedback *model.Feedback) (<-chan AnalyzeEvent, error) {
+	events := make(chan AnalyzeEvent, 16)
+
+	go func() {
+		defer close(events)
+
+		messages := []ChatMessage{
+			{Role: "system", Content: a.buildSystemPrompt()},
+			{Role: "user", Content: a.buildUserPrompt(feedback)},
+		}
+		tools := a.buildTools()
+
+		var usage Usage
+
+		// We limit iterations to prevent infinite loops (and huge bills).
+		maxIterations := 10
+		for i := 0; i < maxIterations; i++ {
+			if a.Budget.nearingInputBudget(usage) {
+				a.summarizeOldToolMessages(ctx, messages)
+			}
+
+			assembled, finishReason, delta, err := a.callLLM(ctx, messages, tools, func(ev AnalyzeEvent) {
+				events <- ev
+			})
+			if err != nil {
+				events <- AnalyzeEvent{Kind: EventError, Err: fmt.Errorf("LLM call failed: %w", err), Usage: &usage}
+				return
+			}
+			usage.InputTokens += delta.InputTokens
+			usage.OutputTokens += delta.OutputTokens
+			usage.USD += delta.USD
+
+			// Add the assistant's reply (which might contain tool calls) to history
+			messages = append(messages, assembled)
+
+			// If "stop", the LLM is done and has produced the final text answer.
+			// If no tool calls, it's also done.
+			if finishReason == "stop" || len(assembled.ToolCalls) == 0 {
+				events <- AnalyzeEvent{Kind: EventFinalAnswer, Content: assembled.Content, Usage: &usage}
+				return
+			}
+
+			if exceeded, reason := a.Budget.exceeded(usage); exceeded {
+				log.Printf("[selfhealing] %s - returning partial result", reason)
+				content := assembled.Content
+				if content == "" {
+					content = "(budget exceeded before a final answer was produced)"
+				}
+				events <- AnalyzeEvent{Kind: EventFinalAnswer, Content: content, Usage: &usage}
+				return
+			}
+
+			// Execute requested tools, feeding each result back to the LLM.
+			// The Role is "tool", and ToolCallID links it back to the specific request.
+			for _, toolCall := range assembled.ToolCalls {
+				toolCall := toolCall
+				events <- AnalyzeEvent{Kind: EventToolCallStarted, Tool: &toolCall}
+				result := a.executeTool(ctx, toolCall)
+				events <- AnalyzeEvent{Kind: EventToolCallResult, Tool: &toolCall, Result: result}
+
+				messages = append(messages, ChatMessage{
+					Role:       "tool",
+					Content:    result,
+					ToolCallID: toolCall.ID,
+				})
+			}
+		}
+
+		// Fallback: If we run out of turns, return the last thing the assistant said.
+		for i := len(messages) - 1; i >= 0; i-- {
+			if messages[i].Role == "assistant" && messages[i].Content != "" {
+				events <- AnalyzeEvent{Kind: EventFinalAnswer, Content: messages[i].Content, Usage: &usage}
+				return
+			}
+		}
+
+		events <- AnalyzeEvent{Kind: EventError, Err: fmt.Errorf("analysis incomplete after %d tool iterations", maxIterations), Usage: &usage}
+	}()
+
+	return events, nil
+}
+
+// buildSystemPrompt sets the agent's persona and operational constraints.
+func (a *LLMAnalyzer) buildSystemPrompt() string {
 	systemPrompt := `You are a senior software engineer analyzing user feedback and bug reports for a web application.
 
 Your job is to:
@@ -146,7 +308,20 @@ IMPORTANT:
 - Be specific - reference line numbers and function names
 - If the feedback is vague, still explore the code and provide useful context`
 
-	// USER PROMPT: The specific task input.
+	if a.index != nil {
+		systemPrompt += `
+
+You also have a search_code tool that semantically searches the codebase by
+meaning rather than filename. Prefer it over guessing directory names: search
+for what the feedback describes, then use get_file_content on the strongest
+hits to read them in full before concluding.`
+	}
+
+	return systemPrompt
+}
+
+// buildUserPrompt renders the specific task input for a piece of feedback.
+func (a *LLMAnalyzer) buildUserPrompt(feedback *model.Feedback) string {
 	userPrompt := fmt.Sprintf(`Analyze this user feedback:
 
 **Title:** %s
@@ -167,9 +342,12 @@ IMPORTANT:
 		userPrompt += "\n\nNote: The user provided minimal description. Focus on exploring the codebase structure and providing an overview of the relevant components based on the title."
 	}
 
-	// TOOL DEFINITIONS: The "Capabilities" we give the agent.
+	return userPrompt
+}
 
-tools := []Tool{
+// buildTools defines the "Capabilities" we give the agent.
+func (a *LLMAnalyzer) buildTools() []Tool {
+	tools := []Tool{
 		{
 			Type: "function",
 			Function: ToolFunction{
@@ -206,127 +384,216 @@ tools := []Tool{
 		},
 	}
 
-	// Initialize conversation history
-	messages := []ChatMessage{
-		{Role: "system", Content: systemPrompt},
-		{Role: "user", Content: userPrompt},
+	// search_code is only offered when the analyzer was built with an
+	// embeddings index (NewLLMAnalyzerWithIndex); otherwise the agent falls
+	// back to list_files/get_file_content alone.
+	if a.index != nil {
+		tools = append(tools, Tool{
+			Type: "function",
+			Function: ToolFunction{
+				Name:        "search_code",
+				Description: "Semantically search the codebase for snippets relevant to a natural-language query (e.g. 'handles feedback submission validation'). Returns the best-matching file paths and line ranges - call get_file_content on the most promising hits to read them in full.",
+				Parameters: map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"query": map[string]interface{}{
+							"type":        "string",
+							"description": "Natural-language description of the code you're looking for.",
+						},
+					},
+					"required": []string{"query"},
+				},
+			},
+		})
 	}
 
-	// THE LOOP:
-	// We limit iterations to prevent infinite loops (and huge bills).
-	maxIterations := 10
-	for i := 0; i < maxIterations; i++ {
-		// 1. Ask the LLM
-		response, err := a.callLLM(ctx, messages, tools)
-		if err != nil {
-			return "", fmt.Errorf("LLM call failed: %w", err)
-		}
-
-		if len(response.Choices) == 0 {
-			return "", fmt.Errorf("no response from LLM")
-		}
+	return tools
+}
 
-		choice := response.Choices[0]
-		// Add the assistant's reply (which might contain tool calls) to history
-		messages = append(messages, choice.Message)
+// callLLM dispatches a chat request to a.providers in order, retrying
+// retryable failures (429/5xx, per RetryableError) on the current provider
+// with exponential backoff and jitter before failing over to the next one.
+// The full message history - including tool-call IDs within it - is
+// preserved across both retries and failover; each Provider is responsible
+// for translating it into its own backend's schema.
+//
+// When onDelta is non-nil and the chosen provider implements
+// StreamingProvider, the request streams and onDelta is invoked for every
+// token as it arrives, then once per tool call after the stream ends.
+// Otherwise the whole reply arrives in one blocking call and onDelta (if
+// set) fires once with the full content and once per decided tool call.
+//
+// The returned Usage is this one call's token/dollar spend (estimated via
+// EstimateTokens and a.Budget's pricing table), for the caller to accumulate
+// towards the whole Analyze call's total.
+func (a *LLMAnalyzer) callLLM(ctx context.Context, messages []ChatMessage, tools []Tool, onDelta func(AnalyzeEvent)) (ChatMessage, string, Usage, error) {
+	if len(a.providers) == 0 {
+		return ChatMessage{}, "", Usage{}, fmt.Errorf("no LLM providers configured")
+	}
+
+	req := ChatRequest{Messages: messages, Tools: tools, MaxTokens: 4000, Temperature: 0.2}
+
+	const maxAttemptsPerProvider = 3
+	var lastErr error
+	for _, provider := range a.providers {
+		for attempt := 0; attempt < maxAttemptsPerProvider; attempt++ {
+			resp, err := a.dispatchOne(ctx, provider, req, onDelta)
+			if err == nil {
+				msg := resp.Choices[0].Message
+				inputTokens := estimateRequestTokens(req)
+				outputTokens := EstimateTokens(msg.Content)
+				for _, tc := range msg.ToolCalls {
+					outputTokens += EstimateTokens(tc.Function.Arguments)
+				}
+				usage := Usage{
+					InputTokens:  inputTokens,
+					OutputTokens: outputTokens,
+					USD:          a.Budget.costFor(provider.Name(), inputTokens, outputTokens),
+				}
+				return msg, resp.Choices[0].FinishReason, usage, nil
+			}
+			lastErr = err
+
+			var retryable *RetryableError
+			if !errors.As(err, &retryable) {
+				// Not a rate-limit/5xx - failing over immediately is
+				// cheaper than burning retries on an error that won't
+				// resolve itself (bad request, auth failure, ...).
+				log.Printf("[selfhealing] provider %s failed (non-retryable): %v", provider.Name(), err)
+				break
+			}
+			if attempt == maxAttemptsPerProvider-1 {
+				log.Printf("[selfhealing] provider %s exhausted retries: %v", provider.Name(), err)
+				break
+			}
 
-		// 2. Check termination condition
-		// If "stop", the LLM is done and has produced the final text answer.
-		// If no tool calls, it's also done.
-		if choice.FinishReason == "stop" || len(choice.Message.ToolCalls) == 0 {
-			return choice.Message.Content, nil
+			backoff := time.Duration(1<<uint(attempt)) * 500 * time.Millisecond
+			jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+			log.Printf("[selfhealing] provider %s attempt %d failed, retrying in %v: %v", provider.Name(), attempt+1, backoff+jitter, err)
+			select {
+			case <-ctx.Done():
+				return ChatMessage{}, "", Usage{}, ctx.Err()
+			case <-time.After(backoff + jitter):
+			}
 		}
+	}
 
-		// 3. Execute requested tools
-		for _, toolCall := range choice.Message.ToolCalls {
-			result := a.executeTool(toolCall)
+	return ChatMessage{}, "", Usage{}, fmt.Errorf("all LLM providers failed: %w", lastErr)
+}
 
-			// 4. Feed result back to LLM
-			// The Role is "tool", and ToolCallID links it back to the specific request.
-			messages = append(messages, ChatMessage{
-				Role:       "tool",
-				Content:    result,
-				ToolCallID: toolCall.ID,
-			})
+// estimateRequestTokens approximates the input token count of a full chat
+// request - the whole message history and tool schemas, not just the
+// newest message - since that's what counts against Budget.MaxInputTokens.
+func estimateRequestTokens(req ChatRequest) int {
+	total := 0
+	for _, m := range req.Messages {
+		total += EstimateTokens(m.Content)
+		for _, tc := range m.ToolCalls {
+			total += EstimateTokens(tc.Function.Name) + EstimateTokens(tc.Function.Arguments)
 		}
 	}
+	for _, t := range req.Tools {
+		total += EstimateTokens(t.Function.Description)
+	}
+	return total
+}
 
-	// Fallback: If we run out of turns, return the last thing the assistant said.
-	if len(messages) > 0 {
-		for i := len(messages) - 1; i >= 0; i-- {
-			if messages[i].Role == "assistant" && messages[i].Content != "" {
-				return messages[i].Content, nil
-			}
+// summarizeOldToolMessages shrinks the prompt when nearing the input token
+// budget by replacing early "tool" messages' content with a short
+// LLM-generated précis, preserving role/ToolCallID so the conversation stays
+// structurally valid. The most recent keepRecentToolMessages tool messages
+// are left untouched, since they're the most likely to still be relevant to
+// what the agent is doing right now.
+func (a *LLMAnalyzer) summarizeOldToolMessages(ctx context.Context, messages []ChatMessage) {
+	const keepRecentToolMessages = 2
+	const summarizeThresholdTokens = 200 // Below this, an extra LLM round-trip isn't worth it.
+
+	var toolIdxs []int
+	for i, m := range messages {
+		if m.Role == "tool" {
+			toolIdxs = append(toolIdxs, i)
 		}
 	}
+	if len(toolIdxs) <= keepRecentToolMessages {
+		return
+	}
 
-	return "", fmt.Errorf("analysis incomplete after %d tool iterations", maxIterations)
+	for _, idx := range toolIdxs[:len(toolIdxs)-keepRecentToolMessages] {
+		msg := &messages[idx]
+		if EstimateTokens(msg.Content) < summarizeThresholdTokens {
+			continue
+		}
+		summary, err := a.summarizeText(ctx, msg.Content)
+		if err != nil {
+			log.Printf("[selfhealing] failed to summarize old tool message, leaving as-is: %v", err)
+			continue
+		}
+		msg.Content = summary
+	}
 }
 
-// callLLM handles the low-level HTTP networking to the inference provider.
-func (a *LLMAnalyzer) callLLM(ctx context.Context, messages []ChatMessage, tools []Tool) (*ChatResponse, error) {
-	reqBody := ChatRequest{
-		Model:       a.model,
-		Messages:    messages,
-		Tools:       tools,
-		MaxTokens:   4000,
-		Temperature: 0.2, // Low temperature for stability during tool use
+// summarizeText asks the primary provider for a short précis of a tool
+// result that's no longer worth keeping in full in the context window.
+func (a *LLMAnalyzer) summarizeText(ctx context.Context, text string) (string, error) {
+	if len(a.providers) == 0 {
+		return "", fmt.Errorf("no LLM providers configured")
 	}
-
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, err
+	req := ChatRequest{
+		Messages: []ChatMessage{
+			{Role: "system", Content: "Summarize the following tool output in 2-3 sentences. Preserve any file paths, function/type names, and line numbers mentioned. Be terse."},
+			{Role: "user", Content: text},
+		},
+		MaxTokens:   200,
+		Temperature: 0,
 	}
-
-	url := strings.TrimSuffix(a.baseURL, "/") + "/chat/completions"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	resp, err := a.providers[0].Chat(ctx, req)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response summarizing tool output")
+	}
+	return "[summarized] " + resp.Choices[0].Message.Content, nil
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+a.apiKey)
-
-	resp, err := a.httpClient.Do(req)
-	if err != nil {
-		return nil, err
+// dispatchOne makes a single attempt against provider, preferring its
+// streaming path when onDelta is set and it supports one.
+func (a *LLMAnalyzer) dispatchOne(ctx context.Context, provider Provider, req ChatRequest, onDelta func(AnalyzeEvent)) (*ChatResponse, error) {
+	if onDelta != nil {
+		if sp, ok := provider.(StreamingProvider); ok {
+			resp, err := sp.ChatStream(ctx, req, onDelta)
+			if err != nil {
+				return nil, err
+			}
+			if len(resp.Choices) == 0 {
+				return nil, fmt.Errorf("%s: no response", provider.Name())
+			}
+			return resp, nil
+		}
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	resp, err := provider.Chat(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("LLM API error (HTTP %d): %s", resp.StatusCode, string(body))
-	}
-
-	var chatResp ChatResponse
-	if err := json.Unmarshal(body, &chatResp); err != nil {
-		return nil, fmt.Errorf("failed to parse response: %w, body: %s", err, string(body))
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%s: no response", provider.Name())
 	}
-
-	// Provider-agnostic error handling
-	if chatResp.Error != nil {
-		switch e := chatResp.Error.(type) {
-		case string:
-			return nil, fmt.Errorf("LLM API error: %s", e)
-		case map[string]interface{}:
-			if msg, ok := e["message"].(string); ok {
-				return nil, fmt.Errorf("LLM API error: %s", msg)
-			}
-			return nil, fmt.Errorf("LLM API error: %v", e)
-		default:
-			return nil, fmt.Errorf("LLM API error: %v", e)
+	if onDelta != nil {
+		msg := resp.Choices[0].Message
+		if msg.Content != "" {
+			onDelta(AnalyzeEvent{Kind: EventTokenDelta, Content: msg.Content})
+		}
+		for _, tc := range msg.ToolCalls {
+			tc := tc
+			onDelta(AnalyzeEvent{Kind: EventToolCallDecided, Tool: &tc})
 		}
 	}
-
-	return &chatResp, nil
+	return resp, nil
 }
 
 // executeTool acts as the router/dispatcher for tool calls.
-func (a *LLMAnalyzer) executeTool(toolCall ToolCall) string {
+func (a *LLMAnalyzer) executeTool(ctx context.Context, toolCall ToolCall) string {
 	var args map[string]string
 	// Tool arguments are always JSON strings
 	if err := json.Unmarshal([]byte(toolCall.Function.Arguments), &args); err != nil {
@@ -344,6 +611,10 @@ func (a *LLMAnalyzer) executeTool(toolCall ToolCall) string {
 		path := args["path"]
 		log.Printf("[selfhealing] Tool list_files: %s", path)
 		result = a.listFiles(path)
+	case "search_code":
+		query := args["query"]
+		log.Printf("[selfhealing] Tool search_code: %s", query)
+		result = a.searchCode(ctx, query)
 	default:
 		log.Printf("[selfhealing] Unknown tool: %s", toolCall.Function.Name)
 		result = fmt.Sprintf("Unknown tool: %s", toolCall.Function.Name)
@@ -352,6 +623,31 @@ func (a *LLMAnalyzer) executeTool(toolCall ToolCall) string {
 	return result
 }
 
+// searchCode implements the search_code tool, formatting CodeIndex hits as
+// plain text the LLM can scan before deciding which file to read in full.
+func (a *LLMAnalyzer) searchCode(ctx context.Context, query string) string {
+	if a.index == nil {
+		return "Error: search_code is not configured for this analyzer"
+	}
+	if query == "" {
+		return "Error: query must not be empty"
+	}
+
+	results, err := a.index.Search(ctx, query)
+	if err != nil {
+		return fmt.Sprintf("Error searching code: %v", err)
+	}
+	if len(results) == 0 {
+		return "No matching code found"
+	}
+
+	var sb strings.Builder
+	for _, r := range results {
+		fmt.Fprintf(&sb, "%s:%d-%d (score %.3f)\n%s\n\n", r.Path, r.StartLine, r.EndLine, r.Score, r.Text)
+	}
+	return truncateToolOutput(sb.String())
+}
+
 // getFileContent implements the file reading tool.
 func (a *LLMAnalyzer) getFileContent(path string) string {
 	if a.sourceDir == "" {
@@ -387,8 +683,8 @@ func (a *LLMAnalyzer) getFileContent(path string) string {
 	if info.IsDir() {
 		return fmt.Sprintf("Error: Path is a directory, not a file: %s", path)
 	}
-	if info.Size() > 100*1024 { // 100KB limit
-		return fmt.Sprintf("Error: File too large (%d bytes, max 100KB)", info.Size())
+	if info.Size() > 2*1024*1024 { // 2MB hard ceiling - even truncated, this is almost certainly a binary or generated file
+		return fmt.Sprintf("Error: File too large (%d bytes, max 2MB)", info.Size())
 	}
 
 	content, err := os.ReadFile(fullPath)
@@ -396,7 +692,10 @@ func (a *LLMAnalyzer) getFileContent(path string) string {
 		return fmt.Sprintf("Error reading file: %v", err)
 	}
 
-	return string(content)
+	// Rather than refusing outright, truncate oversized content: the agent
+	// still gets the start of the file (usually the most useful part) and a
+	// single large read can't blow the whole token budget by itself.
+	return truncateToolOutput(string(content))
 }
 
 // listFiles implements the directory listing tool.
@@ -447,5 +746,19 @@ func (a *LLMAnalyzer) listFiles(path string) string {
 		return "Directory is empty or contains only hidden files"
 	}
 
-	return strings.Join(files, "\n")
+	return truncateToolOutput(strings.Join(files, "\n"))
+}
+
+// maxToolOutputChars caps a single tool result (get_file_content,
+// list_files, search_code) so one call can't dominate the token budget -
+// roughly 5k tokens' worth of characters.
+const maxToolOutputChars = 20000
+
+// truncateToolOutput trims s to maxToolOutputChars, noting how much was cut
+// rather than silently dropping it.
+func truncateToolOutput(s string) string {
+	if len(s) <= maxToolOutputChars {
+		return s
+	}
+	return fmt.Sprintf("%s\n\n... [truncated, %d of %d bytes omitted]", s[:maxToolOutputChars], len(s)-maxToolOutputChars, len(s))
 }
\ No newline at end of file
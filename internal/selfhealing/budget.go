@@ -0,0 +1,76 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+//
+// budget.go caps how many tokens (and how much money) a single Analyze call
+// may spend. Before this, maxIterations in AnalyzeStream was the only
+// guardrail - effective against infinite loops, but not against a single
+// iteration reading a huge file and burning the whole spend in one shot.
+package selfhealing
+
+import "fmt"
+
+// ModelPricing is USD cost per 1,000 tokens for one model.
+type ModelPricing struct {
+	InputPerMille  float64
+	OutputPerMille float64
+}
+
+// Budget caps a single Analyze call's token and dollar spend. LLMAnalyzer's
+// Budget field is nil by default, meaning unlimited, so existing callers
+// keep working without opting in.
+type Budget struct {
+	MaxInputTokens  int
+	MaxOutputTokens int
+	MaxUSD          float64
+	// Pricing is keyed by Provider.Name() (e.g.
+	// "openai:groq/llama-3.3-70b-versatile"). A provider with no entry still
+	// counts towards the token caps, just not MaxUSD.
+	Pricing map[string]ModelPricing
+}
+
+// Usage reports what an Analyze call spent, so callers can record
+// per-feedback cost.
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+	USD          float64
+}
+
+// costFor converts a request/response token count into USD using this
+// budget's pricing table for providerName, or 0 if providerName isn't priced.
+func (b *Budget) costFor(providerName string, inputTokens, outputTokens int) float64 {
+	if b == nil {
+		return 0
+	}
+	pricing, ok := b.Pricing[providerName]
+	if !ok {
+		return 0
+	}
+	return float64(inputTokens)/1000*pricing.InputPerMille + float64(outputTokens)/1000*pricing.OutputPerMille
+}
+
+// exceeded reports whether usage has passed any cap this budget configures.
+func (b *Budget) exceeded(usage Usage) (bool, string) {
+	if b == nil {
+		return false, ""
+	}
+	if b.MaxInputTokens > 0 && usage.InputTokens > b.MaxInputTokens {
+		return true, fmt.Sprintf("input token budget exceeded (%d > %d)", usage.InputTokens, b.MaxInputTokens)
+	}
+	if b.MaxOutputTokens > 0 && usage.OutputTokens > b.MaxOutputTokens {
+		return true, fmt.Sprintf("output token budget exceeded (%d > %d)", usage.OutputTokens, b.MaxOutputTokens)
+	}
+	if b.MaxUSD > 0 && usage.USD > b.MaxUSD {
+		return true, fmt.Sprintf("USD budget exceeded ($%.4f > $%.4f)", usage.USD, b.MaxUSD)
+	}
+	return false, ""
+}
+
+// nearingInputBudget reports whether usage is close enough to
+// MaxInputTokens that AnalyzeStream should summarize older tool results
+// before the next call, instead of waiting until the cap is actually hit.
+func (b *Budget) nearingInputBudget(usage Usage) bool {
+	if b == nil || b.MaxInputTokens <= 0 {
+		return false
+	}
+	return usage.InputTokens > b.MaxInputTokens*8/10
+}
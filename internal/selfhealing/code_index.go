@@ -0,0 +1,368 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+//
+// code_index.go adds search_code, a semantic retrieval tool for the ReAct
+// agent in analyze.go. Without it, the agent can only find relevant source
+// by guessing directory names with list_files and reading files one at a
+// time — this indexes the whole source tree as embedded chunks up front so
+// the agent can jump straight to the handful of files that matter.
+package selfhealing
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// chunkLines/chunkOverlap control how the source tree is windowed before
+// embedding: each chunk is chunkLines long, overlapping the previous one by
+// chunkOverlap lines so a match isn't lost by falling across a boundary.
+const (
+	chunkLines   = 40
+	chunkOverlap = 10
+)
+
+// indexableExtensions restricts chunking to text source files; embedding
+// binaries or lockfiles would waste API calls and pollute search results.
+var indexableExtensions = map[string]bool{
+	".go": true, ".ts": true, ".tsx": true, ".js": true, ".jsx": true,
+	".py": true, ".rs": true, ".html": true, ".css": true, ".sql": true,
+	".md": true, ".json": true, ".yaml": true, ".yml": true,
+}
+
+// CodeChunk is one embedded window of a source file.
+type CodeChunk struct {
+	Path      string    `json:"path"`
+	StartLine int       `json:"startLine"`
+	EndLine   int       `json:"endLine"`
+	Text      string    `json:"text"`
+	Vector    []float64 `json:"vector"`
+}
+
+// CodeSearchResult is one ranked hit returned to the LLM.
+type CodeSearchResult struct {
+	Path      string
+	StartLine int
+	EndLine   int
+	Text      string
+	Score     float64
+}
+
+// codeIndexCache is the on-disk representation of a built CodeIndex, keyed
+// by a hash of every indexed file's mtime so an unchanged tree skips
+// re-embedding on the next run.
+type codeIndexCache struct {
+	MtimeHash string      `json:"mtimeHash"`
+	Chunks    []CodeChunk `json:"chunks"`
+}
+
+// CodeIndex is an in-memory nearest-neighbor index over embedded source
+// chunks. It is built lazily on the first Search call and cached to disk
+// between runs, keyed by sourceDir.
+type CodeIndex struct {
+	sourceDir      string
+	apiKey         string
+	baseURL        string
+	embeddingModel string
+	k              int
+	httpClient     *http.Client
+
+	mu     sync.Mutex
+	chunks []CodeChunk
+	built  bool
+}
+
+// NewCodeIndex creates an index over sourceDir using an OpenAI-compatible
+// embeddings endpoint. The index isn't built (or loaded from cache) until
+// the first call to Search.
+func NewCodeIndex(sourceDir, apiKey, baseURL, embeddingModel string, k int) *CodeIndex {
+	if k <= 0 {
+		k = 5
+	}
+	return &CodeIndex{
+		sourceDir:      sourceDir,
+		apiKey:         apiKey,
+		baseURL:        baseURL,
+		embeddingModel: embeddingModel,
+		k:              k,
+		httpClient:     &http.Client{},
+	}
+}
+
+// Search embeds query and returns the top-K nearest source chunks by cosine
+// similarity, building (or loading from its on-disk cache) the index first.
+func (idx *CodeIndex) Search(ctx context.Context, query string) ([]CodeSearchResult, error) {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if !idx.built {
+		if err := idx.build(ctx); err != nil {
+			return nil, err
+		}
+		idx.built = true
+	}
+
+	queryVec, err := idx.embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to embed query: %w", err)
+	}
+
+	type scored struct {
+		chunk CodeChunk
+		score float64
+	}
+	scoredChunks := make([]scored, 0, len(idx.chunks))
+	for _, c := range idx.chunks {
+		scoredChunks = append(scoredChunks, scored{chunk: c, score: cosineSimilarity(queryVec, c.Vector)})
+	}
+	sort.Slice(scoredChunks, func(i, j int) bool { return scoredChunks[i].score > scoredChunks[j].score })
+
+	k := idx.k
+	if k > len(scoredChunks) {
+		k = len(scoredChunks)
+	}
+	results := make([]CodeSearchResult, 0, k)
+	for _, s := range scoredChunks[:k] {
+		results = append(results, CodeSearchResult{
+			Path:      s.chunk.Path,
+			StartLine: s.chunk.StartLine,
+			EndLine:   s.chunk.EndLine,
+			Text:      s.chunk.Text,
+			Score:     s.score,
+		})
+	}
+	return results, nil
+}
+
+// build loads a matching cache from disk, or else walks sourceDir, chunks
+// every indexable file, embeds each chunk, and saves the result for next
+// time.
+func (idx *CodeIndex) build(ctx context.Context) error {
+	mtimeHash, err := idx.hashMtimes()
+	if err != nil {
+		return fmt.Errorf("failed to hash source tree: %w", err)
+	}
+
+	if cached, ok := idx.loadCache(mtimeHash); ok {
+		idx.chunks = cached
+		return nil
+	}
+
+	chunks, err := idx.chunkFiles()
+	if err != nil {
+		return fmt.Errorf("failed to chunk source tree: %w", err)
+	}
+
+	for i := range chunks {
+		vec, err := idx.embed(ctx, chunks[i].Text)
+		if err != nil {
+			return fmt.Errorf("failed to embed %s:%d-%d: %w", chunks[i].Path, chunks[i].StartLine, chunks[i].EndLine, err)
+		}
+		chunks[i].Vector = vec
+	}
+
+	idx.chunks = chunks
+	idx.saveCache(mtimeHash, chunks)
+	return nil
+}
+
+// walkIndexable calls fn for every file under sourceDir worth indexing,
+// skipping the same hidden/dependency directories the filesystem tools do.
+func (idx *CodeIndex) walkIndexable(fn func(path, rel string, d fs.DirEntry) error) error {
+	return filepath.WalkDir(idx.sourceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		name := d.Name()
+		if d.IsDir() {
+			if strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor" || name == "__pycache__" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !indexableExtensions[strings.ToLower(filepath.Ext(name))] {
+			return nil
+		}
+		rel, err := filepath.Rel(idx.sourceDir, path)
+		if err != nil {
+			rel = path
+		}
+		return fn(path, rel, d)
+	})
+}
+
+// hashMtimes fingerprints the indexable source tree by path+mtime, so build
+// can tell whether a cached index is still valid.
+func (idx *CodeIndex) hashMtimes() (string, error) {
+	h := sha256.New()
+	err := idx.walkIndexable(func(path, rel string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s:%d\n", rel, info.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// chunkFiles splits every indexable file into overlapping line windows.
+// Vectors are left nil; build fills them in afterwards.
+func (idx *CodeIndex) chunkFiles() ([]CodeChunk, error) {
+	var chunks []CodeChunk
+	step := chunkLines - chunkOverlap
+
+	err := idx.walkIndexable(func(path, rel string, d fs.DirEntry) error {
+		info, err := d.Info()
+		if err != nil || info.Size() > 200*1024 {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+
+		lines := strings.Split(string(content), "\n")
+		for start := 0; start < len(lines); start += step {
+			end := start + chunkLines
+			if end > len(lines) {
+				end = len(lines)
+			}
+			chunks = append(chunks, CodeChunk{
+				Path:      rel,
+				StartLine: start + 1,
+				EndLine:   end,
+				Text:      strings.Join(lines[start:end], "\n"),
+			})
+			if end == len(lines) {
+				break
+			}
+		}
+		return nil
+	})
+	return chunks, err
+}
+
+// cachePath derives a stable on-disk location for sourceDir's index cache.
+func (idx *CodeIndex) cachePath() string {
+	absSource, err := filepath.Abs(idx.sourceDir)
+	if err != nil {
+		absSource = idx.sourceDir
+	}
+	h := sha256.Sum256([]byte(absSource))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("feedback-codeindex-%s.json", hex.EncodeToString(h[:8])))
+}
+
+func (idx *CodeIndex) loadCache(mtimeHash string) ([]CodeChunk, bool) {
+	data, err := os.ReadFile(idx.cachePath())
+	if err != nil {
+		return nil, false
+	}
+	var cache codeIndexCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, false
+	}
+	if cache.MtimeHash != mtimeHash {
+		return nil, false
+	}
+	return cache.Chunks, true
+}
+
+func (idx *CodeIndex) saveCache(mtimeHash string, chunks []CodeChunk) {
+	data, err := json.Marshal(codeIndexCache{MtimeHash: mtimeHash, Chunks: chunks})
+	if err != nil {
+		return
+	}
+	if err := os.WriteFile(idx.cachePath(), data, 0644); err != nil {
+		log.Printf("[selfhealing] failed to persist code index cache: %v", err)
+	}
+}
+
+// embeddingsRequest/Response follow the OpenAI-compatible /embeddings
+// schema, mirroring ChatRequest/ChatResponse in analyze.go.
+type embeddingsRequest struct {
+	Model string   `json:"model"`
+	Input []string `json:"input"`
+}
+
+type embeddingsResponse struct {
+	Data []struct {
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Error interface{} `json:"error,omitempty"`
+}
+
+// embed calls the embeddings endpoint for a single piece of text.
+func (idx *CodeIndex) embed(ctx context.Context, text string) ([]float64, error) {
+	jsonBody, err := json.Marshal(embeddingsRequest{Model: idx.embeddingModel, Input: []string{text}})
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(idx.baseURL, "/") + "/embeddings"
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+idx.apiKey)
+
+	resp, err := idx.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("embeddings API error (HTTP %d): %s", resp.StatusCode, string(body))
+	}
+
+	var embResp embeddingsResponse
+	if err := json.Unmarshal(body, &embResp); err != nil {
+		return nil, fmt.Errorf("failed to parse embeddings response: %w, body: %s", err, string(body))
+	}
+	if embResp.Error != nil {
+		return nil, fmt.Errorf("embeddings API error: %v", embResp.Error)
+	}
+	if len(embResp.Data) == 0 {
+		return nil, fmt.Errorf("embeddings API returned no data")
+	}
+	return embResp.Data[0].Embedding, nil
+}
+
+// cosineSimilarity measures how aligned two embedding vectors are, ignoring
+// magnitude, which is the standard metric for comparing text embeddings.
+func cosineSimilarity(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, normA, normB float64
+	for i := range a {
+		dot += a[i] * b[i]
+		normA += a[i] * a[i]
+		normB += b[i] * b[i]
+	}
+	if normA == 0 || normB == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(normA) * math.Sqrt(normB))
+}
@@ -0,0 +1,42 @@
+package selfhealing
+
+// Event is one line of the opencode container's structured NDJSON output
+// protocol: each line on stdout is a JSON object discriminated by its
+// "event" field. Fields beyond Type are only populated for the event kinds
+// that use them.
+//
+//	{"event":"pr_created","url":"...","number":42,"branch":"fix/foo"}
+//	{"event":"files_changed","paths":["internal/foo.go"]}
+//	{"event":"tests","passed":12,"failed":0}
+//	{"event":"log","level":"info","msg":"..."}
+type Event struct {
+	Type string `json:"event"`
+
+	// pr_created
+	URL    string `json:"url,omitempty"`
+	Number int    `json:"number,omitempty"`
+	Branch string `json:"branch,omitempty"`
+
+	// files_changed
+	Paths []string `json:"paths,omitempty"`
+
+	// tests
+	Passed int `json:"passed,omitempty"`
+	Failed int `json:"failed,omitempty"`
+
+	// log
+	Level   string `json:"level,omitempty"`
+	Message string `json:"msg,omitempty"`
+}
+
+// TestSummary is the most recent "tests" event an opencode run reported, if
+// any.
+type TestSummary struct {
+	Passed int `json:"passed"`
+	Failed int `json:"failed"`
+}
+
+// EventCallback receives each Event as parseOutput decodes it, in the order
+// the opencode container emitted them, so a caller can surface progress
+// (e.g. logging) without waiting for parseOutput to finish. May be nil.
+type EventCallback func(Event)
@@ -0,0 +1,202 @@
+package selfhealing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// Executor abstracts how "opencode" mode actually runs the agent. Until now
+// this was hardcoded to shelling out to a script that talks to a local
+// Docker container; Trigger now depends on this interface instead, so
+// switching backends (see buildExecutor) is a config change, not a Trigger
+// code change.
+type Executor interface {
+	// Prepare validates whatever the executor needs before its first Run
+	// (a script file existing, a Kubernetes/Nomad client actually being
+	// constructible). Called once at startup, from NewTrigger and from
+	// Config.Validate.
+	Prepare() error
+	// Run executes one self-healing job against feedback and returns its
+	// captured stdout - ideally the NDJSON event protocol Trigger.parseOutput
+	// decodes (see Event), though it falls back to substring-scraping for
+	// agents that only print plain log lines. Run applies its own timeout
+	// derived from ctx.
+	Run(ctx context.Context, feedback *model.Feedback) (string, error)
+	// HealthCheck reports whether the backend can currently accept work
+	// (container running, cluster/API reachable). Checked by
+	// Trigger.CanTrigger before queuing opencode-mode work, and polled
+	// periodically for the selfhealing_container_up metric.
+	HealthCheck() error
+}
+
+// buildExecutor picks the Executor implementation named by
+// config.Executor. An empty value falls back to "docker" to match the
+// pre-existing hardcoded behavior; an unknown value is an error.
+func buildExecutor(config Config) (Executor, error) {
+	switch config.Executor {
+	case "podman":
+		return NewPodmanExecutor(config.TriggerScript, config.ContainerName, config.Timeout), nil
+	case "k8s":
+		return NewKubernetesJobExecutor(config.KubernetesNamespace, config.KubernetesImage, config.Timeout)
+	case "nomad":
+		return NewNomadExecutor(config.NomadAddr, config.NomadJobPrefix, config.NomadImage, config.Timeout), nil
+	case "docker", "":
+		return NewDockerExecutor(config.TriggerScript, config.ContainerName, config.Timeout), nil
+	default:
+		return nil, fmt.Errorf("unknown SELFHEALING_EXECUTOR %q (want docker, podman, k8s, or nomad)", config.Executor)
+	}
+}
+
+// marshalFeedbackForExecutor serializes the subset of feedback every
+// executor passes to its agent, shared so DockerExecutor/PodmanExecutor/
+// KubernetesJobExecutor/NomadExecutor all send identical payloads.
+func marshalFeedbackForExecutor(feedback *model.Feedback) ([]byte, error) {
+	return json.Marshal(map[string]interface{}{
+		"id":          feedback.ID,
+		"title":       feedback.Title,
+		"description": feedback.Description,
+		"type":        feedback.Type,
+		"url":         feedback.URL,
+		"consoleLogs": feedback.ConsoleLogs,
+	})
+}
+
+// DockerExecutor runs the agent by shelling out to triggerScript, which
+// pipes the feedback into a long-lived Docker container. This is the
+// original (and still default) behavior.
+type DockerExecutor struct {
+	triggerScript string
+	containerName string
+	timeout       time.Duration
+}
+
+// NewDockerExecutor constructs a DockerExecutor.
+func NewDockerExecutor(triggerScript, containerName string, timeout time.Duration) *DockerExecutor {
+	return &DockerExecutor{triggerScript: triggerScript, containerName: containerName, timeout: timeout}
+}
+
+// Prepare checks that triggerScript exists.
+func (e *DockerExecutor) Prepare() error {
+	if _, err := os.Stat(e.triggerScript); os.IsNotExist(err) {
+		return fmt.Errorf("trigger script not found: %s", e.triggerScript)
+	}
+	return nil
+}
+
+// HealthCheck confirms the target container is running via `docker ps`.
+func (e *DockerExecutor) HealthCheck() error {
+	cmd := exec.Command("docker", "ps", "--filter", "name="+e.containerName, "--format", "{{.Names}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("docker ps failed: %w", err)
+	}
+	if strings.TrimSpace(string(output)) != e.containerName {
+		return fmt.Errorf("container %q not running", e.containerName)
+	}
+	return nil
+}
+
+// Run shells out to triggerScript with the serialized feedback as its sole
+// argument, and OPENCODE_CONTAINER in its environment.
+func (e *DockerExecutor) Run(ctx context.Context, feedback *model.Feedback) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	feedbackJSON, err := marshalFeedbackForExecutor(feedback)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize feedback: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, e.triggerScript, string(feedbackJSON))
+	cmd.Env = append(os.Environ(), "OPENCODE_CONTAINER="+e.containerName)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("timeout after %v", e.timeout)
+		}
+		return "", fmt.Errorf("%v: %s", err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
+
+// PodmanExecutor is the rootless-friendly sibling of DockerExecutor: same
+// script-driven shape, but against a `podman` container instead of
+// `docker`. Podman's CLI is largely docker-compatible, so only the binary
+// name changes.
+type PodmanExecutor struct {
+	triggerScript string
+	containerName string
+	timeout       time.Duration
+}
+
+// NewPodmanExecutor constructs a PodmanExecutor.
+func NewPodmanExecutor(triggerScript, containerName string, timeout time.Duration) *PodmanExecutor {
+	return &PodmanExecutor{triggerScript: triggerScript, containerName: containerName, timeout: timeout}
+}
+
+// Prepare checks that triggerScript exists and that the podman binary is on
+// PATH, since podman (unlike docker) isn't guaranteed to be preinstalled.
+func (e *PodmanExecutor) Prepare() error {
+	if _, err := os.Stat(e.triggerScript); os.IsNotExist(err) {
+		return fmt.Errorf("trigger script not found: %s", e.triggerScript)
+	}
+	if _, err := exec.LookPath("podman"); err != nil {
+		return fmt.Errorf("podman binary not found on PATH: %w", err)
+	}
+	return nil
+}
+
+// HealthCheck confirms the target container is running via `podman ps`.
+func (e *PodmanExecutor) HealthCheck() error {
+	cmd := exec.Command("podman", "ps", "--filter", "name="+e.containerName, "--format", "{{.Names}}")
+	output, err := cmd.Output()
+	if err != nil {
+		return fmt.Errorf("podman ps failed: %w", err)
+	}
+	if strings.TrimSpace(string(output)) != e.containerName {
+		return fmt.Errorf("container %q not running", e.containerName)
+	}
+	return nil
+}
+
+// Run shells out to triggerScript the same way DockerExecutor.Run does,
+// passing PODMAN_CONTAINER instead of OPENCODE_CONTAINER so trigger scripts
+// can tell which backend invoked them.
+func (e *PodmanExecutor) Run(ctx context.Context, feedback *model.Feedback) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	feedbackJSON, err := marshalFeedbackForExecutor(feedback)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize feedback: %v", err)
+	}
+
+	cmd := exec.CommandContext(ctx, e.triggerScript, string(feedbackJSON))
+	cmd.Env = append(os.Environ(), "PODMAN_CONTAINER="+e.containerName)
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("timeout after %v", e.timeout)
+		}
+		return "", fmt.Errorf("%v: %s", err, stderr.String())
+	}
+
+	return stdout.String(), nil
+}
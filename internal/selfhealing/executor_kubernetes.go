@@ -0,0 +1,195 @@
+package selfhealing
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
+
+	corev1 "k8s.io/api/core/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// KubernetesJobExecutor runs the agent as a one-shot batch/v1 Job: one Job
+// per feedback item, its output read back from the pod's logs, deleted
+// once it finishes (success or failure) so the cluster doesn't accumulate
+// a Job per analysis forever.
+type KubernetesJobExecutor struct {
+	namespace string
+	image     string
+	timeout   time.Duration
+	clientset *kubernetes.Clientset
+}
+
+// NewKubernetesJobExecutor builds a KubernetesJobExecutor, preferring
+// in-cluster config (the common case: the feedback server itself runs in
+// the cluster it dispatches Jobs into) and falling back to the local
+// kubeconfig for development.
+func NewKubernetesJobExecutor(namespace, image string, timeout time.Duration) (*KubernetesJobExecutor, error) {
+	restConfig, err := rest.InClusterConfig()
+	if err != nil {
+		loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+		restConfig, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{}).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubernetes config: %w", err)
+		}
+	}
+
+	clientset, err := kubernetes.NewForConfig(restConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kubernetes client: %w", err)
+	}
+
+	return &KubernetesJobExecutor{namespace: namespace, image: image, timeout: timeout, clientset: clientset}, nil
+}
+
+// Prepare checks that a namespace and agent image are configured.
+func (e *KubernetesJobExecutor) Prepare() error {
+	if e.namespace == "" {
+		return fmt.Errorf("SELFHEALING_K8S_NAMESPACE is required for the k8s executor")
+	}
+	if e.image == "" {
+		return fmt.Errorf("SELFHEALING_K8S_IMAGE is required for the k8s executor")
+	}
+	return nil
+}
+
+// HealthCheck confirms the target namespace is reachable.
+func (e *KubernetesJobExecutor) HealthCheck() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if _, err := e.clientset.CoreV1().Namespaces().Get(ctx, e.namespace, metav1.GetOptions{}); err != nil {
+		return fmt.Errorf("kubernetes namespace %q unreachable: %w", e.namespace, err)
+	}
+	return nil
+}
+
+// Run creates a Job, waits for its single pod to complete (respecting ctx
+// cancellation), streams back its logs as output, and deletes the Job
+// (foreground propagation, so its pod is cleaned up too) before returning.
+func (e *KubernetesJobExecutor) Run(ctx context.Context, feedback *model.Feedback) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	feedbackJSON, err := marshalFeedbackForExecutor(feedback)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize feedback: %v", err)
+	}
+
+	jobName := fmt.Sprintf("selfhealing-%d-%d", feedback.ID, time.Now().UnixNano())
+	backoffLimit := int32(0)
+
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: e.namespace,
+			Labels:    map[string]string{"app": "selfhealing-agent"},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:  "opencode-agent",
+							Image: e.image,
+							Env: []corev1.EnvVar{
+								{Name: "FEEDBACK_JSON", Value: string(feedbackJSON)},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	created, err := e.clientset.BatchV1().Jobs(e.namespace).Create(ctx, job, metav1.CreateOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to create kubernetes job: %w", err)
+	}
+
+	defer func() {
+		propagation := metav1.DeletePropagationForeground
+		delCtx, delCancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer delCancel()
+		if err := e.clientset.BatchV1().Jobs(e.namespace).Delete(delCtx, created.Name, metav1.DeleteOptions{
+			PropagationPolicy: &propagation,
+		}); err != nil && !apierrors.IsNotFound(err) {
+			fmt.Printf("[selfhealing] failed to delete kubernetes job %s: %v\n", created.Name, err)
+		}
+	}()
+
+	if err := e.waitForCompletion(ctx, created.Name); err != nil {
+		return "", err
+	}
+
+	return e.podLogs(ctx, created.Name)
+}
+
+// waitForCompletion polls the Job's status until it reports either Complete
+// or Failed, or ctx is done.
+func (e *KubernetesJobExecutor) waitForCompletion(ctx context.Context, jobName string) error {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timed out waiting for kubernetes job %s: %w", jobName, ctx.Err())
+		case <-ticker.C:
+			current, err := e.clientset.BatchV1().Jobs(e.namespace).Get(ctx, jobName, metav1.GetOptions{})
+			if err != nil {
+				return fmt.Errorf("failed to poll kubernetes job %s: %w", jobName, err)
+			}
+			for _, cond := range current.Status.Conditions {
+				if cond.Status != corev1.ConditionTrue {
+					continue
+				}
+				if cond.Type == batchv1.JobComplete {
+					return nil
+				}
+				if cond.Type == batchv1.JobFailed {
+					return fmt.Errorf("kubernetes job %s failed: %s", jobName, cond.Message)
+				}
+			}
+		}
+	}
+}
+
+// podLogs finds the single pod created by jobName and returns its combined
+// stdout/stderr log.
+func (e *KubernetesJobExecutor) podLogs(ctx context.Context, jobName string) (string, error) {
+	pods, err := e.clientset.CoreV1().Pods(e.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: "job-name=" + jobName,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list pods for job %s: %w", jobName, err)
+	}
+	if len(pods.Items) == 0 {
+		return "", fmt.Errorf("no pods found for job %s", jobName)
+	}
+
+	stream, err := e.clientset.CoreV1().Pods(e.namespace).GetLogs(pods.Items[0].Name, &corev1.PodLogOptions{}).Stream(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to stream logs for pod %s: %w", pods.Items[0].Name, err)
+	}
+	defer stream.Close()
+
+	var sb strings.Builder
+	reader := bufio.NewReader(stream)
+	if _, err := io.Copy(&sb, reader); err != nil && err != io.EOF {
+		return sb.String(), fmt.Errorf("failed to read logs for pod %s: %w", pods.Items[0].Name, err)
+	}
+
+	return sb.String(), nil
+}
@@ -0,0 +1,255 @@
+package selfhealing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bluefermion/feedback/internal/model"
+)
+
+// nomadJob is the minimal batch/v1-ish job spec NomadExecutor submits via
+// the Nomad HTTP API - just enough fields to run one Docker-driven task,
+// not a general-purpose Nomad job builder.
+type nomadJob struct {
+	ID          string
+	Name        string
+	Type        string
+	Datacenters []string
+	TaskGroups  []nomadTaskGroup
+}
+
+type nomadTaskGroup struct {
+	Name  string
+	Count int
+	Tasks []nomadTask
+}
+
+type nomadTask struct {
+	Name   string
+	Driver string
+	Config map[string]interface{}
+	Env    map[string]string
+}
+
+// nomadAllocation is the subset of Nomad's allocation summary NomadExecutor
+// needs to know when a job's task has finished.
+type nomadAllocation struct {
+	ID           string `json:"ID"`
+	ClientStatus string `json:"ClientStatus"`
+}
+
+// NomadExecutor runs the agent as a one-shot Nomad batch job, submitted and
+// polled via Nomad's HTTP API rather than a client library, since the API
+// is small enough that net/http is simpler than adding a dependency for it.
+type NomadExecutor struct {
+	addr      string // e.g. http://127.0.0.1:4646
+	jobPrefix string
+	image     string
+	timeout   time.Duration
+	client    *http.Client
+}
+
+const nomadTaskName = "opencode-agent"
+
+// NewNomadExecutor constructs a NomadExecutor.
+func NewNomadExecutor(addr, jobPrefix, image string, timeout time.Duration) *NomadExecutor {
+	return &NomadExecutor{
+		addr:      addr,
+		jobPrefix: jobPrefix,
+		image:     image,
+		timeout:   timeout,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Prepare checks that a Nomad address and agent image are configured.
+func (e *NomadExecutor) Prepare() error {
+	if e.addr == "" {
+		return fmt.Errorf("NOMAD_ADDR is required for the nomad executor")
+	}
+	if e.image == "" {
+		return fmt.Errorf("SELFHEALING_NOMAD_IMAGE is required for the nomad executor")
+	}
+	return nil
+}
+
+// HealthCheck confirms the Nomad API is reachable and has an elected leader.
+func (e *NomadExecutor) HealthCheck() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.addr+"/v1/status/leader", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("nomad unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("nomad returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Run submits a batch job, polls its allocation until it finishes, fetches
+// the allocation's stdout, then purges the job so completed runs don't
+// accumulate in Nomad's job list.
+func (e *NomadExecutor) Run(ctx context.Context, feedback *model.Feedback) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	feedbackJSON, err := marshalFeedbackForExecutor(feedback)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize feedback: %v", err)
+	}
+
+	jobID := fmt.Sprintf("%s-%d-%d", e.jobPrefix, feedback.ID, time.Now().UnixNano())
+
+	if err := e.submitJob(ctx, jobID, string(feedbackJSON)); err != nil {
+		return "", err
+	}
+	defer e.purgeJob(jobID)
+
+	allocID, err := e.waitForAllocation(ctx, jobID)
+	if err != nil {
+		return "", err
+	}
+
+	return e.fetchLogs(ctx, allocID)
+}
+
+func (e *NomadExecutor) submitJob(ctx context.Context, jobID, feedbackJSON string) error {
+	job := nomadJob{
+		ID:          jobID,
+		Name:        jobID,
+		Type:        "batch",
+		Datacenters: []string{"dc1"},
+		TaskGroups: []nomadTaskGroup{
+			{
+				Name:  "agent",
+				Count: 1,
+				Tasks: []nomadTask{
+					{
+						Name:   nomadTaskName,
+						Driver: "docker",
+						Config: map[string]interface{}{"image": e.image},
+						Env:    map[string]string{"FEEDBACK_JSON": feedbackJSON},
+					},
+				},
+			},
+		},
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"Job": job})
+	if err != nil {
+		return fmt.Errorf("failed to marshal nomad job spec: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.addr+"/v1/jobs", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to submit nomad job: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("nomad job submission failed (status %d): %s", resp.StatusCode, respBody)
+	}
+	return nil
+}
+
+// waitForAllocation polls the job's allocations until one reports complete
+// or failed, or ctx is done.
+func (e *NomadExecutor) waitForAllocation(ctx context.Context, jobID string) (string, error) {
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("timed out waiting for nomad job %s: %w", jobID, ctx.Err())
+		case <-ticker.C:
+			allocations, err := e.listAllocations(ctx, jobID)
+			if err != nil {
+				return "", err
+			}
+			for _, alloc := range allocations {
+				switch alloc.ClientStatus {
+				case "complete":
+					return alloc.ID, nil
+				case "failed":
+					return "", fmt.Errorf("nomad allocation %s failed", alloc.ID)
+				}
+			}
+		}
+	}
+}
+
+func (e *NomadExecutor) listAllocations(ctx context.Context, jobID string) ([]nomadAllocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.addr+"/v1/job/"+jobID+"/allocations", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to poll nomad allocations for job %s: %w", jobID, err)
+	}
+	defer resp.Body.Close()
+
+	var allocations []nomadAllocation
+	if err := json.NewDecoder(resp.Body).Decode(&allocations); err != nil {
+		return nil, fmt.Errorf("failed to decode nomad allocations for job %s: %w", jobID, err)
+	}
+	return allocations, nil
+}
+
+func (e *NomadExecutor) fetchLogs(ctx context.Context, allocID string) (string, error) {
+	url := fmt.Sprintf("%s/v1/client/fs/logs/%s?task=%s&type=stdout&origin=start&plain=true", e.addr, allocID, nomadTaskName)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch nomad logs for allocation %s: %w", allocID, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read nomad logs for allocation %s: %w", allocID, err)
+	}
+	return string(body), nil
+}
+
+func (e *NomadExecutor) purgeJob(jobID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, e.addr+"/v1/job/"+jobID+"?purge=true", nil)
+	if err != nil {
+		log.Printf("[selfhealing] failed to build nomad purge request for job %s: %v", jobID, err)
+		return
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		log.Printf("[selfhealing] failed to purge nomad job %s: %v", jobID, err)
+		return
+	}
+	resp.Body.Close()
+}
@@ -12,13 +12,9 @@
 package selfhealing
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
 	"log"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
@@ -41,6 +37,12 @@ type Guards struct {
 	baseURL string
 	timeout time.Duration
 	skipAll bool // Emergency hatch to bypass guards during dev/testing
+
+	// selector routes CheckPromptInjection/CheckSafety to the
+	// cheapest registered provider supporting their respective
+	// classification capability, instead of each hand-wiring its own model
+	// name and HTTP call (see ProviderSelector).
+	selector *ProviderSelector
 }
 
 // NewGuards initializes the safety subsystem.
@@ -50,12 +52,32 @@ func NewGuards() *Guards {
 	if baseURL == "" {
 		baseURL = "https://api.demeterics.com/chat/v1"
 	}
+	apiKey := os.Getenv("LLM_API_KEY")
+
+	// We request "Llama Prompt Guard", a small, fast model specifically for
+	// jailbreak/injection detection, and "Llama Guard" for broader content
+	// moderation. Note: 86M parameters is tiny compared to a 12B model,
+	// making prompt-guard both cheaper and faster - its costRank reflects
+	// that.
+	promptGuardModel := "meta-llama/llama-prompt-guard-2-86m"
+	safetyModel := "meta-llama/llama-guard-4-12b"
+	// API Compatibility Adapter: Groq-hosted Demeterics only exposes a
+	// single model identifier covering both use cases.
+	if strings.Contains(baseURL, "demeterics.com") {
+		promptGuardModel = "groq/llama-guard-3-8b"
+		safetyModel = "groq/llama-guard-3-8b"
+	}
+
+	selector := NewProviderSelector()
+	selector.Register(NewOpenAIProvider(apiKey, baseURL, promptGuardModel), 0, "classification", "prompt_injection")
+	selector.Register(NewOpenAIProvider(apiKey, baseURL, safetyModel), 1, "classification", "safety")
 
 	return &Guards{
-		apiKey:  os.Getenv("LLM_API_KEY"),
-		baseURL: baseURL,
-		timeout: 30 * time.Second,
-		skipAll: os.Getenv("SKIP_GUARDS") == "true",
+		apiKey:   apiKey,
+		baseURL:  baseURL,
+		timeout:  30 * time.Second,
+		skipAll:  os.Getenv("SKIP_GUARDS") == "true",
+		selector: selector,
 	}
 }
 
@@ -72,9 +94,13 @@ func (g *Guards) CheckPromptInjection(ctx context.Context, text string) GuardRes
 		return GuardResult{OK: true, Skipped: true, Reason: "no API key configured"}
 	}
 
-	// We request "Llama Prompt Guard", a small, fast model specifically for this task.
-	// Note: 86M parameters is tiny compared to GPT-4, making it very fast and cheap.
-	result, err := g.callGuardModel(ctx, "meta-llama/llama-prompt-guard-2-86m", text)
+	provider, err := g.selector.SelectFor("prompt_injection")
+	if err != nil {
+		log.Printf("Guard check failed (failing open): %v", err)
+		return GuardResult{OK: true, Reason: fmt.Sprintf("guard check failed: %v", err)}
+	}
+
+	result, err := g.callGuardModel(ctx, provider, text)
 	if err != nil {
 		// If guard service is down, we typically log and proceed (fail open) to avoid downtime,
 		// unless security is paramount.
@@ -97,7 +123,12 @@ func (g *Guards) CheckSafety(ctx context.Context, text string) GuardResult {
 	}
 
 	// "Llama Guard" is a fine-tuned version of Llama for content moderation policy enforcement.
-	result, err := g.callGuardModel(ctx, "meta-llama/llama-guard-4-12b", text)
+	provider, err := g.selector.SelectFor("safety")
+	if err != nil {
+		return GuardResult{OK: true, Reason: fmt.Sprintf("safety check failed: %v", err)}
+	}
+
+	result, err := g.callGuardModel(ctx, provider, text)
 	if err != nil {
 		return GuardResult{OK: true, Reason: fmt.Sprintf("safety check failed: %v", err)}
 	}
@@ -127,18 +158,10 @@ func (g *Guards) RunAllGuards(ctx context.Context, text string) (GuardResult, er
 	return GuardResult{OK: true}, nil
 }
 
-// callGuardModel handles the API interaction with the inference provider.
-func (g *Guards) callGuardModel(ctx context.Context, model, text string) (GuardResult, error) {
-	// API Compatibility Adapter:
-	// Different providers name models differently. We map canonical names to provider specific ones.
-	if strings.Contains(g.baseURL, "demeterics.com") {
-		// Groq hosting uses simplified identifiers.
-		if strings.Contains(model, "prompt-guard") || strings.Contains(model, "llama-guard") {
-			model = "groq/llama-guard-3-8b"
-		}
-	}
-
-	log.Printf("[guard] Calling model: %s", model)
+// callGuardModel dispatches a classification request to provider (selected
+// by g.selector) and interprets its response.
+func (g *Guards) callGuardModel(ctx context.Context, provider Provider, text string) (GuardResult, error) {
+	log.Printf("[guard] Calling model: %s", provider.Name())
 
 	// Truncation: Guard models typically have shorter context windows.
 	// Analyzing the first 4000 characters is usually sufficient to catch attacks.
@@ -146,63 +169,28 @@ func (g *Guards) callGuardModel(ctx context.Context, model, text string) (GuardR
 		text = text[:4000]
 	}
 
-	// Construct Standard Chat Completion Request
-	reqBody := map[string]interface{}{
-		"model": model,
-		"messages": []map[string]string{
-			{"role": "user", "content": text},
-		},
-		"max_tokens":  100, // Safety responses are very short ("safe" or "unsafe")
-		"temperature": 0,   // Deterministic output is required for classifiers
-	}
+	ctx, cancel := context.WithTimeout(ctx, g.timeout)
+	defer cancel()
 
-	jsonBody, err := json.Marshal(reqBody)
-	if err != nil {
-		return GuardResult{}, err
-	}
-
-	url := strings.TrimSuffix(g.baseURL, "/") + "/chat/completions"
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
-	if err != nil {
-		return GuardResult{}, err
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+g.apiKey)
-
-	client := &http.Client{Timeout: g.timeout}
-	resp, err := client.Do(req)
+	resp, err := provider.Chat(ctx, ChatRequest{
+		Messages: []ChatMessage{
+			{Role: "user", Content: text},
+		},
+		MaxTokens:   100, // Safety responses are very short ("safe" or "unsafe")
+		Temperature: 0,   // Deterministic output is required for classifiers
+	})
 	if err != nil {
 		return GuardResult{}, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return GuardResult{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
-	}
-
-	// Parse Response
-	var result struct {
-		Choices []struct {
-			Message struct {
-				Content string `json:"content"`
-			}
-		}
-	}
-
-	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
-		return GuardResult{}, err
-	}
 
-	if len(result.Choices) == 0 {
+	if len(resp.Choices) == 0 {
 		return GuardResult{OK: true}, nil
 	}
 
-	content := strings.TrimSpace(result.Choices[0].Message.Content)
+	content := strings.TrimSpace(resp.Choices[0].Message.Content)
 	contentLower := strings.ToLower(content)
 
-	log.Printf("[guard] Model %s response: %s", model, content)
+	log.Printf("[guard] Model %s response: %s", provider.Name(), content)
 
 	guardResult := GuardResult{OK: true}
 
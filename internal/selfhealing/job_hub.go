@@ -0,0 +1,179 @@
+package selfhealing
+
+import (
+	"sync"
+	"time"
+)
+
+// Self-healing progress stages, published to JobHub as a job moves through
+// HandleSubmit's guard check and Trigger's worker pipeline. Mirrors the
+// states HandleSelfHealingStream's SSE clients render as a progress bar.
+const (
+	StageQueued         = "queued"
+	StageGuardCheck     = "guard-check"
+	StageAnalyzing      = "analyzing"
+	StagePatchGenerated = "patch-generated"
+	StagePROpened       = "pr-opened"
+	StageLog            = "log"
+	StageDone           = "done"
+	StageFailed         = "failed"
+)
+
+// hubEventBufferSize bounds each job's ring buffer of past events, so a
+// client reconnecting with Last-Event-ID can replay recent history without
+// the hub retaining every event a long-running job ever emitted.
+const hubEventBufferSize = 256
+
+// jobHubFinishedTTL and jobHubSweepInterval bound how long a finished job's
+// stream lingers in memory after StageDone/StageFailed - long enough for a
+// client reconnecting with Last-Event-ID just after completion to still
+// replay the terminal event, short enough that streams don't accumulate
+// forever as more feedback gets submitted. Mirrors the
+// ipRateLimiter.cleanupLoop/sweepIdempotencyKeysLoop eviction pattern.
+const (
+	jobHubFinishedTTL   = 10 * time.Minute
+	jobHubSweepInterval = 1 * time.Minute
+)
+
+// StreamEvent is one progress update on a feedback's self-healing job.
+type StreamEvent struct {
+	ID        int64
+	Stage     string
+	Message   string
+	Timestamp time.Time
+}
+
+// JobHub lets HTTP handlers subscribe to progress events for a feedback's
+// self-healing job, independent of (and outliving) the single-shot result
+// channel TriggerAsync returns - multiple clients can attach or reattach to
+// the same job (e.g. a browser tab reconnecting after a network blip),
+// following the same separately-exposed-job-status pattern as the vulndb
+// worker.
+type JobHub struct {
+	mu      sync.Mutex
+	streams map[int64]*jobStream
+}
+
+// jobStream is one feedback ID's event history and live subscribers.
+type jobStream struct {
+	mu   sync.Mutex
+	seq  int64
+	buf  []StreamEvent
+	subs map[chan StreamEvent]struct{}
+
+	// finishedAt is set once the job reaches StageDone/StageFailed, and is
+	// how sweepLoop decides this stream is safe to evict after
+	// jobHubFinishedTTL. Zero means still in progress.
+	finishedAt time.Time
+}
+
+// NewJobHub creates an empty JobHub and starts its background sweep of
+// finished jobs' streams.
+func NewJobHub() *JobHub {
+	h := &JobHub{streams: make(map[int64]*jobStream)}
+	go h.sweepLoop()
+	return h
+}
+
+// stream returns feedbackID's stream, creating it on first use.
+func (h *JobHub) stream(feedbackID int64) *jobStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s, ok := h.streams[feedbackID]
+	if !ok {
+		s = &jobStream{subs: make(map[chan StreamEvent]struct{})}
+		h.streams[feedbackID] = s
+	}
+	return s
+}
+
+// Publish appends an event to feedbackID's stream and fans it out to every
+// currently-subscribed client. Slow subscribers that can't keep up simply
+// miss the live event - they can still catch up via Subscribe's replay
+// from the ring buffer using the event ID they last saw.
+func (h *JobHub) Publish(feedbackID int64, stage, message string) {
+	s := h.stream(feedbackID)
+
+	s.mu.Lock()
+	s.seq++
+	event := StreamEvent{ID: s.seq, Stage: stage, Message: message, Timestamp: time.Now()}
+	s.buf = append(s.buf, event)
+	if len(s.buf) > hubEventBufferSize {
+		s.buf = s.buf[len(s.buf)-hubEventBufferSize:]
+	}
+	if stage == StageDone || stage == StageFailed {
+		s.finishedAt = time.Now()
+	}
+	subs := make([]chan StreamEvent, 0, len(s.subs))
+	for ch := range s.subs {
+		subs = append(subs, ch)
+	}
+	s.mu.Unlock()
+
+	for _, ch := range subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe attaches to feedbackID's stream. replay holds any buffered
+// events after lastEventID (0 replays everything buffered), for resuming a
+// connection via the SSE Last-Event-ID header. live delivers further
+// events as they're published; call unsubscribe once the caller is done
+// reading it.
+func (h *JobHub) Subscribe(feedbackID int64, lastEventID int64) (replay []StreamEvent, live <-chan StreamEvent, unsubscribe func()) {
+	s := h.stream(feedbackID)
+	ch := make(chan StreamEvent, 16)
+
+	s.mu.Lock()
+	for _, e := range s.buf {
+		if e.ID > lastEventID {
+			replay = append(replay, e)
+		}
+	}
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+
+	unsubscribe = func() {
+		s.mu.Lock()
+		delete(s.subs, ch)
+		s.mu.Unlock()
+	}
+
+	return replay, ch, unsubscribe
+}
+
+// sweepLoop evicts finished jobs' streams on a timer, for the lifetime of
+// the process.
+func (h *JobHub) sweepLoop() {
+	ticker := time.NewTicker(jobHubSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.sweep()
+	}
+}
+
+// sweep deletes every stream that reached a terminal stage more than
+// jobHubFinishedTTL ago. A stream still awaiting StageDone/StageFailed (or
+// that reached one too recently) is left alone regardless of how long it's
+// been since NewJobHub started, so a slow-running job can't get evicted out
+// from under its own subscribers.
+func (h *JobHub) sweep() {
+	cutoff := time.Now().Add(-jobHubFinishedTTL)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for id, s := range h.streams {
+		s.mu.Lock()
+		finished := !s.finishedAt.IsZero() && s.finishedAt.Before(cutoff)
+		s.mu.Unlock()
+		if finished {
+			delete(h.streams, id)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package selfhealing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker coordinates cooldown/single-flight state across every process
+// running a Trigger, so running multiple replicas doesn't double-trigger
+// analyses or let two of them drive the same shared opencode container at
+// once. InMemoryLocker (the default, unchanged single-process behavior) and
+// RedisLocker (opt-in via REDIS_URL) both implement it.
+type Locker interface {
+	// TryLockRunning attempts to acquire the cross-replica "an opencode
+	// analysis is in flight" lock for ttl, returning false if another
+	// replica already holds it. The caller is expected to refresh the
+	// lock periodically (see Trigger's heartbeat goroutine) and to call
+	// UnlockRunning once the job finishes, so a crashed holder's lock
+	// still expires via ttl rather than wedging every other replica out
+	// forever.
+	TryLockRunning(ttl time.Duration) (bool, error)
+	// RefreshRunning extends the running lock's TTL. Called periodically
+	// while a job is executing.
+	RefreshRunning(ttl time.Duration) error
+	// UnlockRunning releases the running lock early, once a job finishes
+	// well before its TTL would otherwise expire it.
+	UnlockRunning() error
+
+	// RecordRun stores now as the last trigger time, shared across every
+	// replica, so Cooldown is enforced fleet-wide rather than per-process.
+	RecordRun(now time.Time) error
+	// CooldownRemaining returns how much of cooldown remains since the
+	// last RecordRun, or zero if none has happened yet or it has elapsed.
+	CooldownRemaining(cooldown time.Duration) (time.Duration, error)
+}
+
+// InMemoryLocker is the default Locker: process-local, identical to the
+// sync.Mutex-guarded behavior Trigger used before distributed locking was
+// added. Correct for a single replica; two replicas each running their own
+// InMemoryLocker can still double-trigger, which is exactly why RedisLocker
+// exists for multi-replica deployments.
+type InMemoryLocker struct {
+	mu      sync.Mutex
+	running bool
+	lastRun time.Time
+}
+
+// NewInMemoryLocker constructs an InMemoryLocker.
+func NewInMemoryLocker() *InMemoryLocker {
+	return &InMemoryLocker{}
+}
+
+func (l *InMemoryLocker) TryLockRunning(ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.running {
+		return false, nil
+	}
+	l.running = true
+	return true, nil
+}
+
+func (l *InMemoryLocker) RefreshRunning(ttl time.Duration) error {
+	return nil
+}
+
+func (l *InMemoryLocker) UnlockRunning() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.running = false
+	return nil
+}
+
+func (l *InMemoryLocker) RecordRun(now time.Time) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lastRun = now
+	return nil
+}
+
+func (l *InMemoryLocker) CooldownRemaining(cooldown time.Duration) (time.Duration, error) {
+	l.mu.Lock()
+	lastRun := l.lastRun
+	l.mu.Unlock()
+
+	if lastRun.IsZero() {
+		return 0, nil
+	}
+	remaining := cooldown - time.Since(lastRun)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, nil
+}
+
+// RedisLocker is the cross-replica Locker: the running lock is a `SET NX
+// PX`-style key (via go-redis's SetNX with a TTL) so exactly one replica
+// holds it at a time and it self-expires if that replica crashes before
+// calling UnlockRunning, and lastRun is a separate key every replica reads
+// for cooldown enforcement.
+//
+// The running lock's value is a random per-acquisition token (see
+// compareAndDeleteScript/compareAndExpireScript) rather than a constant, so
+// that if the TTL expires out from under a stalled holder and a second
+// replica acquires the lock, the first replica's eventual RefreshRunning or
+// UnlockRunning - called against a lock it no longer holds - is a safe no-op
+// instead of refreshing or deleting the second replica's lock.
+type RedisLocker struct {
+	client    *redis.Client
+	keyPrefix string
+
+	mu    sync.Mutex
+	token string
+}
+
+// NewRedisLocker builds a RedisLocker from a REDIS_URL-style connection
+// string (redis://[:password@]host:port/db). keyPrefix namespaces its two
+// keys so multiple services/environments can share one Redis instance.
+func NewRedisLocker(redisURL, keyPrefix string) (*RedisLocker, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid REDIS_URL: %w", err)
+	}
+	return &RedisLocker{client: redis.NewClient(opts), keyPrefix: keyPrefix}, nil
+}
+
+func (l *RedisLocker) runningKey() string { return l.keyPrefix + ":running" }
+func (l *RedisLocker) lastRunKey() string { return l.keyPrefix + ":last_run" }
+
+// newToken generates a random identifier for one lock acquisition, so
+// RefreshRunning/UnlockRunning can tell "the lock I acquired" apart from "a
+// lock some other replica acquired after mine expired".
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate lock token: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// compareAndExpireScript extends the running key's TTL only if it still
+// holds this holder's token, so a replica whose lock already expired and
+// was reacquired elsewhere can't keep extending someone else's lock.
+const compareAndExpireScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+else
+	return 0
+end`
+
+// compareAndDeleteScript deletes the running key only if it still holds
+// this holder's token, so a replica whose lock already expired and was
+// reacquired elsewhere can't delete the new holder's lock.
+const compareAndDeleteScript = `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+else
+	return 0
+end`
+
+func (l *RedisLocker) TryLockRunning(ttl time.Duration) (bool, error) {
+	token, err := newToken()
+	if err != nil {
+		return false, err
+	}
+
+	ok, err := l.client.SetNX(context.Background(), l.runningKey(), token, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis SET NX failed: %w", err)
+	}
+	if ok {
+		l.mu.Lock()
+		l.token = token
+		l.mu.Unlock()
+	}
+	return ok, nil
+}
+
+func (l *RedisLocker) RefreshRunning(ttl time.Duration) error {
+	l.mu.Lock()
+	token := l.token
+	l.mu.Unlock()
+
+	if err := l.client.Eval(context.Background(), compareAndExpireScript,
+		[]string{l.runningKey()}, token, ttl.Milliseconds()).Err(); err != nil {
+		return fmt.Errorf("redis EXPIRE failed: %w", err)
+	}
+	return nil
+}
+
+func (l *RedisLocker) UnlockRunning() error {
+	l.mu.Lock()
+	token := l.token
+	l.mu.Unlock()
+
+	if err := l.client.Eval(context.Background(), compareAndDeleteScript,
+		[]string{l.runningKey()}, token).Err(); err != nil {
+		return fmt.Errorf("redis DEL failed: %w", err)
+	}
+	return nil
+}
+
+func (l *RedisLocker) RecordRun(now time.Time) error {
+	if err := l.client.Set(context.Background(), l.lastRunKey(), now.UnixNano(), 0).Err(); err != nil {
+		return fmt.Errorf("redis SET failed: %w", err)
+	}
+	return nil
+}
+
+func (l *RedisLocker) CooldownRemaining(cooldown time.Duration) (time.Duration, error) {
+	val, err := l.client.Get(context.Background(), l.lastRunKey()).Result()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("redis GET failed: %w", err)
+	}
+
+	nanos, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", l.lastRunKey(), val, err)
+	}
+
+	remaining := cooldown - time.Since(time.Unix(0, nanos))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, nil
+}
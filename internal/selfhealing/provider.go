@@ -0,0 +1,263 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+//
+// provider.go decouples LLMAnalyzer from any one inference backend. Before
+// this, the OpenAI-compatible /chat/completions schema, base URL, and model
+// name were hard-coded directly into LLMAnalyzer. Provider lets us plug in
+// Anthropic, Ollama, or anything else without touching the ReAct loop -
+// analyze.go only ever deals in the OpenAI-compatible ChatRequest/ChatResponse
+// types already defined there; each Provider translates to/from its own
+// backend's native schema.
+package selfhealing
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Provider is anything that can turn a ChatRequest into a ChatResponse.
+type Provider interface {
+	Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error)
+	Name() string
+}
+
+// StreamingProvider is implemented by providers that can stream token deltas
+// as they arrive. Providers that don't implement it still work fine with
+// AnalyzeStream - their whole reply just surfaces as a single token_delta
+// event instead of many, once the blocking call returns.
+type StreamingProvider interface {
+	Provider
+	ChatStream(ctx context.Context, req ChatRequest, onDelta func(AnalyzeEvent)) (*ChatResponse, error)
+}
+
+// RetryableError marks a Provider failure worth retrying (rate limiting, a
+// transient 5xx) as opposed to a permanent one (bad request, auth failure)
+// that should fail over to the next provider immediately rather than burn
+// retry attempts on this one.
+type RetryableError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *RetryableError) Error() string { return e.Err.Error() }
+func (e *RetryableError) Unwrap() error { return e.Err }
+
+// isRetryableStatus reports whether an HTTP status is worth retrying:
+// rate-limited (429) or a transient server error (5xx).
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= 500
+}
+
+// -----------------------------------------------------------------------------
+// OpenAI-compatible provider
+// -----------------------------------------------------------------------------
+
+// OpenAIProvider talks to any OpenAI-compatible /chat/completions endpoint
+// (OpenAI itself, Groq, Demeterics, ...). ChatRequest/ChatResponse already
+// match its native schema, so no translation is needed.
+type OpenAIProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOpenAIProvider constructs a provider that targets model at baseURL.
+func NewOpenAIProvider(apiKey, baseURL, model string) *OpenAIProvider {
+	return &OpenAIProvider{
+		apiKey:     apiKey,
+		baseURL:    baseURL,
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+// Name identifies this provider in logs (e.g. "openai:groq/llama-3.3-70b-versatile").
+func (p *OpenAIProvider) Name() string { return fmt.Sprintf("openai:%s", p.model) }
+
+// Chat sends a single blocking chat completion request.
+func (p *OpenAIProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	req.Model = p.model
+	req.Stream = false
+
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(p.baseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := fmt.Errorf("openai provider error (HTTP %d): %s", resp.StatusCode, string(body))
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, &RetryableError{StatusCode: resp.StatusCode, Err: apiErr}
+		}
+		return nil, apiErr
+	}
+
+	var chatResp ChatResponse
+	if err := json.Unmarshal(body, &chatResp); err != nil {
+		return nil, fmt.Errorf("failed to parse openai response: %w, body: %s", err, string(body))
+	}
+	if chatResp.Error != nil {
+		return nil, fmt.Errorf("openai provider error: %v", chatResp.Error)
+	}
+	return &chatResp, nil
+}
+
+// chatStreamChunk is one `data: {...}` line of an OpenAI-compatible SSE chat
+// completion stream.
+type chatStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content,omitempty"`
+			ToolCalls []struct {
+				Index    int `json:"index"`
+				ID       string `json:"id,omitempty"`
+				Type     string `json:"type,omitempty"`
+				Function struct {
+					Name      string `json:"name,omitempty"`
+					Arguments string `json:"arguments,omitempty"`
+				} `json:"function,omitempty"`
+			} `json:"tool_calls,omitempty"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Error interface{} `json:"error,omitempty"`
+}
+
+// ChatStream issues a streaming chat completion request and parses the
+// response as server-sent events, invoking onDelta for every content token
+// as it arrives and once per assembled tool call after the stream ends, then
+// returns the fully assembled assistant message and finish_reason.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req ChatRequest, onDelta func(AnalyzeEvent)) (*ChatResponse, error) {
+	req.Model = p.model
+	req.Stream = true
+
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(p.baseURL, "/") + "/chat/completions"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	httpReq.Header.Set("Accept", "text/event-stream")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		apiErr := fmt.Errorf("openai provider error (HTTP %d): %s", resp.StatusCode, string(body))
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, &RetryableError{StatusCode: resp.StatusCode, Err: apiErr}
+		}
+		return nil, apiErr
+	}
+
+	assembled := ChatMessage{Role: "assistant"}
+	// toolCalls accumulates partial tool_calls by index, since providers
+	// stream a single call's id/name/arguments across several chunks.
+	var toolCalls []ToolCall
+	finishReason := ""
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || !strings.HasPrefix(line, "data:") {
+			continue
+		}
+		payload := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if payload == "[DONE]" {
+			break
+		}
+
+		var chunk chatStreamChunk
+		if err := json.Unmarshal([]byte(payload), &chunk); err != nil {
+			continue // Ignore malformed/keep-alive lines rather than aborting the whole stream.
+		}
+		if chunk.Error != nil {
+			return nil, fmt.Errorf("openai provider error: %v", chunk.Error)
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		choice := chunk.Choices[0]
+		if choice.FinishReason != nil {
+			finishReason = *choice.FinishReason
+		}
+		if choice.Delta.Content != "" {
+			assembled.Content += choice.Delta.Content
+			if onDelta != nil {
+				onDelta(AnalyzeEvent{Kind: EventTokenDelta, Content: choice.Delta.Content})
+			}
+		}
+		for _, tc := range choice.Delta.ToolCalls {
+			for len(toolCalls) <= tc.Index {
+				toolCalls = append(toolCalls, ToolCall{Type: "function"})
+			}
+			if tc.ID != "" {
+				toolCalls[tc.Index].ID = tc.ID
+			}
+			if tc.Type != "" {
+				toolCalls[tc.Index].Type = tc.Type
+			}
+			if tc.Function.Name != "" {
+				toolCalls[tc.Index].Function.Name += tc.Function.Name
+			}
+			if tc.Function.Arguments != "" {
+				toolCalls[tc.Index].Function.Arguments += tc.Function.Arguments
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading stream: %w", err)
+	}
+
+	assembled.ToolCalls = toolCalls
+	for _, tc := range toolCalls {
+		tc := tc
+		if onDelta != nil {
+			onDelta(AnalyzeEvent{Kind: EventToolCallDecided, Tool: &tc})
+		}
+	}
+
+	out := &ChatResponse{}
+	out.Choices = append(out.Choices, struct {
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	}{Message: assembled, FinishReason: finishReason})
+	return out, nil
+}
@@ -0,0 +1,253 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+//
+// provider_anthropic.go adapts the Anthropic Messages API to the
+// OpenAI-compatible ChatRequest/ChatResponse schema the rest of the package
+// speaks. Anthropic differs in three ways that matter here: the system
+// prompt is a top-level field rather than a "system"-role message, tool
+// calls/results live inside a message's "content" array (as "tool_use" and
+// "tool_result" blocks) instead of dedicated tool_calls/tool fields, and tool
+// results are sent back as role "user" rather than role "tool".
+package selfhealing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+const anthropicVersion = "2023-06-01"
+
+// AnthropicProvider talks to the Anthropic Messages API (api.anthropic.com/v1/messages).
+type AnthropicProvider struct {
+	apiKey     string
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewAnthropicProvider constructs a provider targeting model (e.g.
+// "claude-3-5-sonnet-20241022") against the Anthropic API.
+func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
+	return &AnthropicProvider{
+		apiKey:     apiKey,
+		baseURL:    "https://api.anthropic.com",
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+// Name identifies this provider in logs (e.g. "anthropic:claude-3-5-sonnet-20241022").
+func (p *AnthropicProvider) Name() string { return fmt.Sprintf("anthropic:%s", p.model) }
+
+// anthropicRequest mirrors the subset of the Messages API request schema
+// this adapter needs.
+type anthropicRequest struct {
+	Model       string             `json:"model"`
+	System      string             `json:"system,omitempty"`
+	Messages    []anthropicMessage `json:"messages"`
+	Tools       []anthropicTool    `json:"tools,omitempty"`
+	MaxTokens   int                `json:"max_tokens"`
+	Temperature float64            `json:"temperature,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string             `json:"role"` // "user" or "assistant"
+	Content []anthropicContent `json:"content"`
+}
+
+// anthropicContent is a tagged union over Anthropic's content block types;
+// only the fields relevant to each Type are populated.
+type anthropicContent struct {
+	Type      string          `json:"type"` // "text", "tool_use", or "tool_result"
+	Text      string          `json:"text,omitempty"`
+	ID        string          `json:"id,omitempty"`         // tool_use: the call ID
+	Name      string          `json:"name,omitempty"`       // tool_use: the function name
+	Input     json.RawMessage `json:"input,omitempty"`      // tool_use: arguments as a JSON object
+	ToolUseID string          `json:"tool_use_id,omitempty"` // tool_result: which tool_use this answers
+	Content   string          `json:"content,omitempty"`     // tool_result: the result text
+}
+
+type anthropicTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	InputSchema map[string]interface{} `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	Content    []anthropicContent `json:"content"`
+	StopReason string             `json:"stop_reason"`
+	Error      *struct {
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// Chat translates req into an Anthropic Messages API request, sends it, and
+// translates the reply back into the OpenAI-compatible ChatResponse shape.
+func (p *AnthropicProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	areq, err := toAnthropicRequest(req, p.model)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBody, err := json.Marshal(areq)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(p.baseURL, "/") + "/v1/messages"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", anthropicVersion)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := fmt.Errorf("anthropic provider error (HTTP %d): %s", resp.StatusCode, string(body))
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, &RetryableError{StatusCode: resp.StatusCode, Err: apiErr}
+		}
+		return nil, apiErr
+	}
+
+	var aresp anthropicResponse
+	if err := json.Unmarshal(body, &aresp); err != nil {
+		return nil, fmt.Errorf("failed to parse anthropic response: %w, body: %s", err, string(body))
+	}
+	if aresp.Error != nil {
+		return nil, fmt.Errorf("anthropic provider error: %s", aresp.Error.Message)
+	}
+
+	return fromAnthropicResponse(aresp), nil
+}
+
+// toAnthropicRequest translates the OpenAI-compatible ChatRequest - in
+// particular its flat message history and tool_calls/tool messages - into
+// Anthropic's system-field-plus-content-blocks schema.
+func toAnthropicRequest(req ChatRequest, model string) (*anthropicRequest, error) {
+	areq := &anthropicRequest{
+		Model:       model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+	}
+	if areq.MaxTokens == 0 {
+		areq.MaxTokens = 4000
+	}
+
+	for _, t := range req.Tools {
+		areq.Tools = append(areq.Tools, anthropicTool{
+			Name:        t.Function.Name,
+			Description: t.Function.Description,
+			InputSchema: t.Function.Parameters,
+		})
+	}
+
+	var systemParts []string
+	for _, m := range req.Messages {
+		switch m.Role {
+		case "system":
+			systemParts = append(systemParts, m.Content)
+		case "tool":
+			// Anthropic has no "tool" role - a tool result is a content
+			// block inside a "user" message, keyed back to the call via
+			// tool_use_id.
+			areq.Messages = append(areq.Messages, anthropicMessage{
+				Role: "user",
+				Content: []anthropicContent{{
+					Type:      "tool_result",
+					ToolUseID: m.ToolCallID,
+					Content:   m.Content,
+				}},
+			})
+		case "assistant":
+			var blocks []anthropicContent
+			if m.Content != "" {
+				blocks = append(blocks, anthropicContent{Type: "text", Text: m.Content})
+			}
+			for _, tc := range m.ToolCalls {
+				blocks = append(blocks, anthropicContent{
+					Type:  "tool_use",
+					ID:    tc.ID,
+					Name:  tc.Function.Name,
+					Input: json.RawMessage(orEmptyObject(tc.Function.Arguments)),
+				})
+			}
+			areq.Messages = append(areq.Messages, anthropicMessage{Role: "assistant", Content: blocks})
+		default: // "user"
+			areq.Messages = append(areq.Messages, anthropicMessage{
+				Role:    "user",
+				Content: []anthropicContent{{Type: "text", Text: m.Content}},
+			})
+		}
+	}
+	areq.System = strings.Join(systemParts, "\n\n")
+
+	return areq, nil
+}
+
+// orEmptyObject guards against json.RawMessage("") being invalid JSON, which
+// would otherwise happen for a tool call whose arguments weren't assembled
+// for some reason.
+func orEmptyObject(s string) string {
+	if strings.TrimSpace(s) == "" {
+		return "{}"
+	}
+	return s
+}
+
+// fromAnthropicResponse translates an Anthropic reply - a mix of "text" and
+// "tool_use" content blocks - back into a single OpenAI-compatible
+// ChatMessage with a flat Content string and ToolCalls slice.
+func fromAnthropicResponse(aresp anthropicResponse) *ChatResponse {
+	msg := ChatMessage{Role: "assistant"}
+	var textParts []string
+	for _, block := range aresp.Content {
+		switch block.Type {
+		case "text":
+			textParts = append(textParts, block.Text)
+		case "tool_use":
+			tc := ToolCall{ID: block.ID, Type: "function"}
+			tc.Function.Name = block.Name
+			if len(block.Input) > 0 {
+				tc.Function.Arguments = string(block.Input)
+			} else {
+				tc.Function.Arguments = "{}"
+			}
+			msg.ToolCalls = append(msg.ToolCalls, tc)
+		}
+	}
+	msg.Content = strings.Join(textParts, "")
+
+	finishReason := "stop"
+	switch aresp.StopReason {
+	case "tool_use":
+		finishReason = "tool_calls"
+	case "end_turn", "stop_sequence":
+		finishReason = "stop"
+	case "max_tokens":
+		finishReason = "length"
+	}
+
+	resp := &ChatResponse{}
+	resp.Choices = append(resp.Choices, struct {
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	}{Message: msg, FinishReason: finishReason})
+	return resp
+}
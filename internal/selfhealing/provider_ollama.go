@@ -0,0 +1,168 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+//
+// provider_ollama.go adapts a local Ollama instance's /api/chat endpoint to
+// the OpenAI-compatible ChatRequest/ChatResponse schema. Ollama's tool
+// calling is close to OpenAI's but encodes arguments as a JSON object
+// rather than a JSON-encoded string, and doesn't assign tool calls an ID -
+// we synthesize one so the rest of the package can still key tool results
+// back to a specific call.
+package selfhealing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OllamaProvider talks to a local (or remote) Ollama server's /api/chat endpoint.
+type OllamaProvider struct {
+	baseURL    string
+	model      string
+	httpClient *http.Client
+}
+
+// NewOllamaProvider constructs a provider targeting model on an Ollama
+// server at baseURL (e.g. "http://localhost:11434").
+func NewOllamaProvider(baseURL, model string) *OllamaProvider {
+	return &OllamaProvider{
+		baseURL:    baseURL,
+		model:      model,
+		httpClient: &http.Client{},
+	}
+}
+
+// Name identifies this provider in logs (e.g. "ollama:llama3.3").
+func (p *OllamaProvider) Name() string { return fmt.Sprintf("ollama:%s", p.model) }
+
+type ollamaMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content,omitempty"`
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+type ollamaToolCall struct {
+	Function ollamaFunctionCall `json:"function"`
+}
+
+type ollamaFunctionCall struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments"`
+}
+
+type ollamaTool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ollamaChatRequest struct {
+	Model    string          `json:"model"`
+	Messages []ollamaMessage `json:"messages"`
+	Tools    []ollamaTool    `json:"tools,omitempty"`
+	Stream   bool            `json:"stream"`
+}
+
+type ollamaChatResponse struct {
+	Message struct {
+		Role      string           `json:"role"`
+		Content   string           `json:"content"`
+		ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+	} `json:"message"`
+	DoneReason string `json:"done_reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Chat translates req into an Ollama /api/chat request, sends it, and
+// translates the reply back into the OpenAI-compatible ChatResponse shape.
+func (p *OllamaProvider) Chat(ctx context.Context, req ChatRequest) (*ChatResponse, error) {
+	oreq := ollamaChatRequest{Model: p.model, Stream: false}
+
+	for _, m := range req.Messages {
+		om := ollamaMessage{Role: m.Role, Content: m.Content}
+		for _, tc := range m.ToolCalls {
+			var args map[string]interface{}
+			if err := json.Unmarshal([]byte(tc.Function.Arguments), &args); err != nil {
+				args = map[string]interface{}{}
+			}
+			om.ToolCalls = append(om.ToolCalls, ollamaToolCall{
+				Function: ollamaFunctionCall{Name: tc.Function.Name, Arguments: args},
+			})
+		}
+		oreq.Messages = append(oreq.Messages, om)
+	}
+	for _, t := range req.Tools {
+		oreq.Tools = append(oreq.Tools, ollamaTool{Type: "function", Function: t.Function})
+	}
+
+	jsonBody, err := json.Marshal(oreq)
+	if err != nil {
+		return nil, err
+	}
+
+	url := strings.TrimSuffix(p.baseURL, "/") + "/api/chat"
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		apiErr := fmt.Errorf("ollama provider error (HTTP %d): %s", resp.StatusCode, string(body))
+		if isRetryableStatus(resp.StatusCode) {
+			return nil, &RetryableError{StatusCode: resp.StatusCode, Err: apiErr}
+		}
+		return nil, apiErr
+	}
+
+	var oresp ollamaChatResponse
+	if err := json.Unmarshal(body, &oresp); err != nil {
+		return nil, fmt.Errorf("failed to parse ollama response: %w, body: %s", err, string(body))
+	}
+	if oresp.Error != "" {
+		return nil, fmt.Errorf("ollama provider error: %s", oresp.Error)
+	}
+
+	msg := ChatMessage{Role: "assistant", Content: oresp.Message.Content}
+	for i, tc := range oresp.Message.ToolCalls {
+		argsJSON, err := json.Marshal(tc.Function.Arguments)
+		if err != nil {
+			argsJSON = []byte("{}")
+		}
+		msg.ToolCalls = append(msg.ToolCalls, ToolCall{
+			// Ollama doesn't assign tool calls an ID; synthesize one so
+			// callers can still match a "tool" result message back to it.
+			ID:   fmt.Sprintf("ollama-call-%d", i),
+			Type: "function",
+			Function: struct {
+				Name      string `json:"name"`
+				Arguments string `json:"arguments"`
+			}{Name: tc.Function.Name, Arguments: string(argsJSON)},
+		})
+	}
+
+	finishReason := "stop"
+	if len(msg.ToolCalls) > 0 {
+		finishReason = "tool_calls"
+	}
+
+	chatResp := &ChatResponse{}
+	chatResp.Choices = append(chatResp.Choices, struct {
+		Message      ChatMessage `json:"message"`
+		FinishReason string      `json:"finish_reason"`
+	}{Message: msg, FinishReason: finishReason})
+	return chatResp, nil
+}
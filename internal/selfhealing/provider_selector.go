@@ -0,0 +1,54 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+package selfhealing
+
+import "fmt"
+
+// ProviderSelector picks a Provider by declared capability (e.g.
+// "classification", "tool_use") rather than every caller hand-wiring a
+// specific provider, so something like Guards' cheap classification calls
+// can be routed to the cheapest model that actually supports them instead
+// of reusing whatever provider the main ReAct agent happens to use.
+type ProviderSelector struct {
+	entries []providerEntry
+}
+
+type providerEntry struct {
+	provider     Provider
+	capabilities map[string]bool
+	costRank     int // Lower is cheaper; breaks ties between providers sharing a capability.
+}
+
+// NewProviderSelector creates an empty selector; use Register to add providers.
+func NewProviderSelector() *ProviderSelector {
+	return &ProviderSelector{}
+}
+
+// Register adds provider to the selector, tagged with the capabilities it
+// supports and a cost rank (lower = cheaper) used to break ties when more
+// than one registered provider supports the requested capability.
+func (s *ProviderSelector) Register(provider Provider, costRank int, capabilities ...string) {
+	caps := make(map[string]bool, len(capabilities))
+	for _, c := range capabilities {
+		caps[c] = true
+	}
+	s.entries = append(s.entries, providerEntry{provider: provider, capabilities: caps, costRank: costRank})
+}
+
+// SelectFor returns the cheapest registered provider that supports
+// capability, or an error if none do.
+func (s *ProviderSelector) SelectFor(capability string) (Provider, error) {
+	var best *providerEntry
+	for i := range s.entries {
+		e := &s.entries[i]
+		if !e.capabilities[capability] {
+			continue
+		}
+		if best == nil || e.costRank < best.costRank {
+			best = e
+		}
+	}
+	if best == nil {
+		return nil, fmt.Errorf("no provider registered for capability %q", capability)
+	}
+	return best.provider, nil
+}
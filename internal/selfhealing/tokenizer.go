@@ -0,0 +1,49 @@
+// Package selfhealing implements the "Agentic" logic of the application.
+//
+// tokenizer.go provides a dependency-free token count estimate for budget
+// enforcement in analyze.go/budget.go. It is NOT a literal BPE tokenizer -
+// vendoring a real tiktoken vocabulary (cl100k_base alone is on the order of
+// 100k merge rules) is out of scope here. Instead it approximates token
+// count from text structure, which only needs to be right within a safety
+// margin for budget tracking, not exact for billing.
+package selfhealing
+
+import "unicode"
+
+// EstimateTokens approximates how many tokens a BPE tokenizer (e.g.
+// tiktoken's cl100k_base) would produce for text. Modern BPE tokenizers
+// average roughly 4 characters per token for English prose and code, but
+// punctuation tends to become its own token rather than folding into a
+// word, so word runs and punctuation are counted separately rather than
+// using one flat character-count ratio.
+func EstimateTokens(text string) int {
+	if text == "" {
+		return 0
+	}
+
+	total := 0
+	runes := []rune(text)
+	i := 0
+	for i < len(runes) {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i])) {
+				i++
+			}
+			wordTokens := (i - start + 3) / 4
+			if wordTokens == 0 {
+				wordTokens = 1
+			}
+			total += wordTokens
+		default:
+			// Punctuation/symbols are usually their own token.
+			total++
+			i++
+		}
+	}
+	return total
+}
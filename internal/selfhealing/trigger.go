@@ -11,18 +11,19 @@
 package selfhealing
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/bluefermion/feedback/internal/metrics"
 	"github.com/bluefermion/feedback/internal/model"
+	"github.com/bluefermion/feedback/internal/requestid"
 )
 
 // Config aggregates all settings for the self-healing subsystem.
@@ -32,64 +33,271 @@ type Config struct {
 	Mode          string        // "analyze" (Go-native LLM) or "opencode" (External Docker container)
 	SourceDir     string        // Where is the code? (for "analyze" mode)
 	RepoDir       string        // Where is the git repo? (for "opencode" mode)
-	TriggerScript string        // Bridge script to call Docker
-	ContainerName string        // Name of the persistent agent container
-	SkipGuards    bool          // Danger: Disable safety checks (dev only)
+	TriggerScript string        // Bridge script to call Docker/Podman (docker/podman executors)
+	ContainerName string        // Name of the persistent agent container (docker/podman executors)
+	// Executor selects the "opencode" mode backend: "docker" (default),
+	// "podman", "k8s", or "nomad". See selfhealing.buildExecutor.
+	Executor string
+	// Kubernetes executor settings, used when Executor == "k8s".
+	KubernetesNamespace string
+	KubernetesImage     string
+	// Nomad executor settings, used when Executor == "nomad".
+	NomadAddr      string
+	NomadImage     string
+	NomadJobPrefix string
+	SkipGuards     bool // Danger: Disable safety checks (dev only)
 	DryRun        bool          // Simulation mode: log what would happen but don't do it
 	Cooldown      time.Duration // Rate limiting: Prevent burning API credits
 	Timeout       time.Duration // Watchdog timer: Kill stuck processes
 	AdminEmails   []string      // Authorization: Who can trigger costly agents?
 	AllowedTypes  []string      // Filtering: usually only "bug" triggers analysis
-	// LLM API settings
+	// WorkerConcurrency bounds how many jobs the durable queue (see the
+	// workerLoop goroutines NewTrigger starts) runs at once. Kept at 1 by default for "opencode"
+	// mode, since that mode drives a single shared Docker container;
+	// "analyze" mode is stateless per call and can usually afford more.
+	WorkerConcurrency int
+	// MaxAttempts caps how many times a failed job is retried before it's
+	// marked dead, so a permanently broken trigger script/LLM credential
+	// can't retry forever and burn API budget.
+	MaxAttempts int
+	// RetryBaseDelay is the base of the exponential backoff applied between
+	// retries (backoff = RetryBaseDelay * 2^(attempts-1)).
+	RetryBaseDelay time.Duration
+	// MetricsPollInterval controls how often Trigger refreshes its gauge
+	// metrics (active job count, cooldown remaining, container health).
+	// Container health in particular needs its own poll cadence so a
+	// /metrics scrape never itself shells out to `docker ps`.
+	MetricsPollInterval time.Duration
+	// RedisURL, when set, switches Trigger from its default in-process
+	// Locker to a Redis-backed one, so Cooldown and the "one opencode
+	// analysis in flight" constraint hold across every replica instead of
+	// per-process. Empty means single-process behavior, unchanged.
+	RedisURL string
+	// LockKeyPrefix namespaces the Redis locker's keys so multiple
+	// services/environments can share one Redis instance.
+	LockKeyPrefix string
+	// LLM API settings (primary/OpenAI-compatible provider)
 	LLMAPIKey  string
 	LLMBaseURL string
 	LLMModel   string
+	// LLMEmbeddingModel enables the search_code tool when non-empty (see
+	// selfhealing.NewLLMAnalyzerWithIndex). LLMEmbeddingK is how many
+	// nearest chunks search_code returns per query.
+	LLMEmbeddingModel string
+	LLMEmbeddingK     int
+	// Fallback providers, tried in order after the primary OpenAI-compatible
+	// one fails (see selfhealing.Provider). Each is
+	// only added to the analyzer's provider list if its credential/URL is set.
+	AnthropicAPIKey string
+	AnthropicModel  string
+	OllamaBaseURL   string
+	OllamaModel     string
+	// Budget caps token/dollar spend per Analyze call (see
+	// selfhealing.Budget). Zero disables the corresponding cap.
+	BudgetMaxInputTokens  int
+	BudgetMaxOutputTokens int
+	BudgetMaxUSD          float64
 }
 
 // DefaultConfig builds the configuration from Environment Variables.
 // This follows the 12-Factor App config principle.
 func DefaultConfig() Config {
 	return Config{
-		Enabled:       os.Getenv("OPENCODE_ENABLED") == "true",
-		Mode:          getEnvOrDefault("SELFHEALING_MODE", "analyze"),
-		SourceDir:     getEnvOrDefault("SOURCE_DIR", "."),
-		RepoDir:       getEnvOrDefault("OPENCODE_REPO_DIR", ""),
-		TriggerScript: getEnvOrDefault("TRIGGER_SCRIPT", "./scripts/trigger-analysis.sh"),
-		ContainerName: getEnvOrDefault("OPENCODE_CONTAINER", "opencode-selfhealing"),
-		SkipGuards:    os.Getenv("SKIP_GUARDS") == "true",
-		DryRun:        os.Getenv("DRY_RUN") == "true",
-		Cooldown:      time.Hour,
-		Timeout:       30 * time.Minute,
-		AdminEmails:   parseCSV(os.Getenv("ADMIN_EMAILS")),
-		AllowedTypes:  parseAllowedTypes(os.Getenv("SELFHEALING_TYPES")),
-		LLMAPIKey:     os.Getenv("LLM_API_KEY"),
-		LLMBaseURL:    getEnvOrDefault("LLM_BASE_URL", "https://api.demeterics.com/chat/v1"),
-		LLMModel:      getEnvOrDefault("LLM_MODEL", "groq/llama-3.3-70b-versatile"),
-	}
-}
-
-// Trigger is the singleton service that manages the lifecycle of analysis jobs.
+		Enabled:           os.Getenv("OPENCODE_ENABLED") == "true",
+		Mode:              getEnvOrDefault("SELFHEALING_MODE", "analyze"),
+		SourceDir:         getEnvOrDefault("SOURCE_DIR", "."),
+		RepoDir:           getEnvOrDefault("OPENCODE_REPO_DIR", ""),
+		TriggerScript:     getEnvOrDefault("TRIGGER_SCRIPT", "./scripts/trigger-analysis.sh"),
+		ContainerName:     getEnvOrDefault("OPENCODE_CONTAINER", "opencode-selfhealing"),
+		Executor:          getEnvOrDefault("SELFHEALING_EXECUTOR", "docker"),
+		KubernetesNamespace: getEnvOrDefault("SELFHEALING_K8S_NAMESPACE", "default"),
+		KubernetesImage:     os.Getenv("SELFHEALING_K8S_IMAGE"),
+		NomadAddr:           getEnvOrDefault("NOMAD_ADDR", "http://127.0.0.1:4646"),
+		NomadImage:          os.Getenv("SELFHEALING_NOMAD_IMAGE"),
+		NomadJobPrefix:      getEnvOrDefault("SELFHEALING_NOMAD_JOB_PREFIX", "opencode-selfhealing"),
+		SkipGuards:        os.Getenv("SKIP_GUARDS") == "true",
+		DryRun:            os.Getenv("DRY_RUN") == "true",
+		Cooldown:          time.Hour,
+		Timeout:           30 * time.Minute,
+		AdminEmails:       parseCSV(os.Getenv("ADMIN_EMAILS")),
+		AllowedTypes:      parseAllowedTypes(os.Getenv("SELFHEALING_TYPES")),
+		WorkerConcurrency: getEnvIntOrDefault("SELFHEALING_WORKER_CONCURRENCY", 1),
+		MaxAttempts:       getEnvIntOrDefault("SELFHEALING_MAX_ATTEMPTS", 3),
+		RetryBaseDelay:    getEnvDurationOrDefault("SELFHEALING_RETRY_BASE_DELAY", 30*time.Second),
+		MetricsPollInterval: getEnvDurationOrDefault("SELFHEALING_METRICS_POLL_INTERVAL", 15*time.Second),
+		RedisURL:            os.Getenv("REDIS_URL"),
+		LockKeyPrefix:       getEnvOrDefault("SELFHEALING_LOCK_KEY_PREFIX", "selfhealing"),
+		LLMAPIKey:         os.Getenv("LLM_API_KEY"),
+		LLMBaseURL:        getEnvOrDefault("LLM_BASE_URL", "https://api.demeterics.com/chat/v1"),
+		LLMModel:          getEnvOrDefault("LLM_MODEL", "groq/llama-3.3-70b-versatile"),
+		LLMEmbeddingModel: os.Getenv("LLM_EMBEDDING_MODEL"),
+		LLMEmbeddingK:     getEnvIntOrDefault("LLM_EMBEDDING_K", 5),
+		AnthropicAPIKey:   os.Getenv("ANTHROPIC_API_KEY"),
+		AnthropicModel:    getEnvOrDefault("ANTHROPIC_MODEL", "claude-3-5-sonnet-20241022"),
+		OllamaBaseURL:     os.Getenv("OLLAMA_BASE_URL"),
+		OllamaModel:       getEnvOrDefault("OLLAMA_MODEL", "llama3.3"),
+
+		BudgetMaxInputTokens:  getEnvIntOrDefault("LLM_BUDGET_MAX_INPUT_TOKENS", 100000),
+		BudgetMaxOutputTokens: getEnvIntOrDefault("LLM_BUDGET_MAX_OUTPUT_TOKENS", 8000),
+		BudgetMaxUSD:          getEnvFloatOrDefault("LLM_BUDGET_MAX_USD", 0.50),
+	}
+}
+
+// JobStore is the persistence dependency Trigger needs: enough of
+// repository.SQLiteRepository to load the feedback a queued job refers to,
+// persist its analysis, and drive the jobs table itself. A small
+// consumer-owned interface, same pattern as handler.ErrorStore.
+type JobStore interface {
+	GetByID(id int64) (*model.Feedback, error)
+	UpdateAnalysis(id int64, analysis string) error
+
+	CreateJob(job *model.Job) (int64, error)
+	ClaimNextJob() (*model.Job, error)
+	GetJobByID(id int64) (*model.Job, error)
+	ListJobs(limit, offset int) ([]*model.Job, error)
+	FinishJob(id int64, state model.JobState, resultJSON, lastError string) error
+	RequeueJob(id int64, nextAttemptAt time.Time, lastError string) error
+	ResetRunningJobs() (int, error)
+}
+
+// Trigger is the service that manages the lifecycle of analysis jobs. It
+// owns a bounded pool of worker goroutines that pull queued jobs from store
+// rather than running each TriggerAsync call in its own goroutine, so a
+// crashed process loses no in-flight work (see NewTrigger's startup
+// recovery pass) and concurrency is capped regardless of how many feedback
+// submissions arrive at once.
 type Trigger struct {
 	config    Config
-	lastRun   time.Time
-	// Mutex protects shared state (lastRun, isRunning) from concurrent access
-	// if multiple HTTP requests hit CanTrigger/Execute simultaneously.
-	mu        sync.Mutex
-	isRunning bool
+	store     JobStore
+	collector metrics.Collector
+	// executor runs "opencode" mode work; nil when config.Mode != "opencode".
+	executor Executor
+	// locker enforces Cooldown and single-flight opencode execution across
+	// every replica (see selfhealing.Locker); defaults to an in-process
+	// InMemoryLocker unless config.RedisURL is set.
+	locker Locker
+
+	// mu protects cancelRunning and waiters from concurrent access by
+	// multiple worker goroutines and HTTP handlers.
+	mu sync.Mutex
+	// cancelRunning cancels the context of a specific in-flight job, keyed
+	// by job ID, so Wait can force every still-running job to give up its
+	// subprocess/HTTP calls once the drain timeout expires.
+	cancelRunning map[int64]context.CancelFunc
+	// waiters delivers the terminal Result for a job back to whichever
+	// TriggerAsync call enqueued it, if that caller is still waiting on the
+	// returned channel. Jobs recovered from a crash (no in-process caller)
+	// simply have no entry here; their result is still persisted and
+	// visible via GetJobByID.
+	waiters map[int64]chan Result
+
+	// wake nudges idle workers to re-poll the queue immediately instead of
+	// waiting for the next poll tick. Buffered 1 and non-blocking: workers
+	// only need to know "something changed", not how many times.
+	wake chan struct{}
+
+	// wg tracks jobs currently executing (not the idle worker-pool
+	// goroutines themselves), so Wait can block shutdown until they finish
+	// or are cancelled.
+	wg sync.WaitGroup
+
+	// hub publishes per-job progress (queued, analyzing, patch-generated,
+	// ...) for HandleSelfHealingStream's SSE subscribers, keyed by feedback
+	// ID rather than job ID since that's what the stream's URL carries.
+	hub *JobHub
+}
+
+// NewTrigger constructs a Trigger backed by store, resets any job a prior
+// crash left stuck in state='running', and starts config.WorkerConcurrency
+// worker goroutines polling the queue. collector may be nil, in which case
+// metrics are silently discarded (see metrics.NewNoop).
+func NewTrigger(config Config, store JobStore, collector metrics.Collector) *Trigger {
+	if config.WorkerConcurrency <= 0 {
+		config.WorkerConcurrency = 1
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = 3
+	}
+	if config.RetryBaseDelay <= 0 {
+		config.RetryBaseDelay = 30 * time.Second
+	}
+	if config.MetricsPollInterval <= 0 {
+		config.MetricsPollInterval = 15 * time.Second
+	}
+	if collector == nil {
+		collector = metrics.NewNoop()
+	}
+	if config.LockKeyPrefix == "" {
+		config.LockKeyPrefix = "selfhealing"
+	}
+
+	locker := buildLocker(config)
+
+	var executor Executor
+	if config.Mode == "opencode" {
+		built, err := buildExecutor(config)
+		if err != nil {
+			log.Printf("[selfhealing] failed to build %q executor: %v", config.Executor, err)
+		} else if err := built.Prepare(); err != nil {
+			log.Printf("[selfhealing] executor %q failed prepare: %v", config.Executor, err)
+		} else {
+			executor = built
+		}
+	}
+
+	t := &Trigger{
+		config:        config,
+		store:         store,
+		collector:     collector,
+		executor:      executor,
+		locker:        locker,
+		cancelRunning: make(map[int64]context.CancelFunc),
+		waiters:       make(map[int64]chan Result),
+		wake:          make(chan struct{}, 1),
+		hub:           NewJobHub(),
+	}
+
+	if reset, err := store.ResetRunningJobs(); err != nil {
+		log.Printf("[selfhealing] failed to reset running jobs on startup: %v", err)
+	} else if reset > 0 {
+		log.Printf("[selfhealing] requeued %d job(s) left running by a previous crash", reset)
+	}
+
+	for i := 0; i < config.WorkerConcurrency; i++ {
+		go t.workerLoop()
+	}
+
+	t.reportMetrics()
+	go t.metricsLoop()
+
+	return t
 }
 
-// NewTrigger constructor.
-func NewTrigger(config Config) *Trigger {
-	return &Trigger{
-		config: config,
+// buildLocker picks the Locker implementation Trigger coordinates through:
+// RedisLocker when config.RedisURL is set (multi-replica deployments), or
+// the in-process InMemoryLocker otherwise - identical to Trigger's
+// single-process behavior before distributed locking existed.
+func buildLocker(config Config) Locker {
+	if config.RedisURL == "" {
+		return NewInMemoryLocker()
 	}
+	locker, err := NewRedisLocker(config.RedisURL, config.LockKeyPrefix)
+	if err != nil {
+		log.Printf("[selfhealing] failed to build redis locker, falling back to in-process locking: %v", err)
+		return NewInMemoryLocker()
+	}
+	return locker
 }
 
 // Result is the structured output of a self-healing job.
 // We return this via a channel to the caller (who may be logging it or updating the DB).
 type Result struct {
-	Triggered   bool      `json:"triggered"`
-	Success     bool      `json:"success"`
+	Triggered bool `json:"triggered"`
+	Success   bool `json:"success"`
+	// RequestID is the correlation ID of the HTTP request that triggered this
+	// job (see internal/requestid), so its log lines can be tied back to the
+	// originating request even though it runs on its own background context.
+	RequestID   string    `json:"request_id,omitempty"`
 	Message     string    `json:"message"`
 	PRNumber    int       `json:"pr_number,omitempty"`
 	PRURL       string    `json:"pr_url,omitempty"`
@@ -98,6 +306,14 @@ type Result struct {
 	CompletedAt time.Time `json:"completed_at,omitempty"`
 	Output      string    `json:"output,omitempty"` // The raw LLM analysis text
 	Error       string    `json:"error,omitempty"`
+	Usage       Usage     `json:"usage,omitempty"` // Token/dollar spend for "analyze" mode; zero for "opencode" mode
+	// FilesChanged, Tests, and Events are populated for "opencode" mode by
+	// parseOutput decoding the container's NDJSON event protocol (see
+	// Event); they're empty for "analyze" mode and for older opencode
+	// agents that only emit plain log lines.
+	FilesChanged []string     `json:"files_changed,omitempty"`
+	Tests        *TestSummary `json:"tests,omitempty"`
+	Events       []Event      `json:"events,omitempty"`
 }
 
 // CanTrigger acts as a Policy Enforcement Point (PEP).
@@ -113,12 +329,13 @@ func (t *Trigger) CanTrigger(feedback *model.Feedback) (bool, string) {
 		if !t.isAdmin(feedback.UserEmail) {
 			return false, "opencode mode requires admin privileges"
 		}
-		// Infrastructure Health Checks
-		if _, err := os.Stat(t.config.TriggerScript); os.IsNotExist(err) {
-			return false, "trigger script not found"
+		// Infrastructure Health Check, delegated to whichever backend
+		// config.Executor selected (see buildExecutor).
+		if t.executor == nil {
+			return false, "opencode executor not configured"
 		}
-		if !t.isContainerRunning() {
-			return false, "opencode container not running"
+		if err := t.executor.HealthCheck(); err != nil {
+			return false, fmt.Sprintf("opencode executor unhealthy: %v", err)
 		}
 	} else {
 		// "analyze" mode is read-only (safe for public demos), just needs API key.
@@ -132,117 +349,291 @@ func (t *Trigger) CanTrigger(feedback *model.Feedback) (bool, string) {
 		return false, fmt.Sprintf("feedback type '%s' not allowed for self-healing", feedback.Type)
 	}
 
-	// Rate Limiting (Thread-Safe)
-	t.mu.Lock()
-	defer t.mu.Unlock()
-
-	if t.isRunning {
-		return false, "self-healing already in progress"
-	}
-
-	if time.Since(t.lastRun) < t.config.Cooldown {
-		remaining := t.config.Cooldown - time.Since(t.lastRun)
+	// Rate Limiting. Note this no longer checks for an in-flight job: the
+	// durable queue (and WorkerConcurrency) now own that concern, so
+	// multiple qualifying submissions within the same cooldown window
+	// simply queue up rather than being rejected outright. Consulting
+	// t.locker instead of a local timestamp means Cooldown holds across
+	// every replica, not just this process.
+	remaining, err := t.locker.CooldownRemaining(t.config.Cooldown)
+	if err != nil {
+		log.Printf("[selfhealing] failed to check cooldown, allowing trigger: %v", err)
+	} else if remaining > 0 {
 		return false, fmt.Sprintf("cooldown active, %v remaining", remaining.Round(time.Minute))
 	}
 
 	return true, ""
 }
 
-// isContainerRunning checks Docker status via shell command.
-func (t *Trigger) isContainerRunning() bool {
-	// We use 'docker ps' with a filter to strictly match the container name.
-	cmd := exec.Command("docker", "ps", "--filter", "name="+t.config.ContainerName, "--format", "{{.Names}}")
-	output, err := cmd.Output()
+// TriggerAsync enqueues a job for feedback and returns a channel that
+// receives its terminal Result once a worker runs it to completion (success,
+// or failure after exhausting retries). Enqueueing itself is just a DB
+// insert and returns immediately; the actual analysis happens later on the
+// worker pool, so callers that don't care about the outcome can safely
+// ignore the returned channel.
+func (t *Trigger) TriggerAsync(ctx context.Context, feedback *model.Feedback) <-chan Result {
+	resultCh := make(chan Result, 1)
+	reqID := requestid.FromContext(ctx)
+
+	job := &model.Job{
+		FeedbackID: feedback.ID,
+		Mode:       t.config.Mode,
+		RequestID:  reqID,
+	}
+	id, err := t.store.CreateJob(job)
 	if err != nil {
-		return false
+		resultCh <- Result{
+			RequestID: reqID,
+			Message:   "failed to enqueue self-healing job",
+			Error:     err.Error(),
+			StartedAt: time.Now(),
+		}
+		close(resultCh)
+		return resultCh
 	}
-	return strings.TrimSpace(string(output)) == t.config.ContainerName
+
+	t.mu.Lock()
+	t.waiters[id] = resultCh
+	t.mu.Unlock()
+
+	t.hub.Publish(feedback.ID, StageQueued, "self-healing job queued")
+	t.signalWake()
+
+	return resultCh
 }
 
-// TriggerAsync launches the analysis in a goroutine and returns a channel for the result.
-// This is the "Fire and Forget" (with optional notification) pattern.
-func (t *Trigger) TriggerAsync(ctx context.Context, feedback *model.Feedback) <-chan Result {
-	resultCh := make(chan Result, 1)
+// Hub returns the JobHub that publishes this Trigger's per-feedback
+// progress events, for HandleSelfHealingStream's SSE subscribers.
+func (t *Trigger) Hub() *JobHub {
+	return t.hub
+}
 
+// signalWake nudges an idle worker to re-poll immediately. Non-blocking:
+// if a wake is already pending, workers will see it and there's nothing
+// more to signal.
+func (t *Trigger) signalWake() {
+	select {
+	case t.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Wait blocks until every job currently executing finishes, or until ctx is
+// done, whichever comes first. In the latter case it cancels each running
+// job's context - which, via the context.WithTimeout each Executor.Run/
+// runAnalyze already derives from it, kills the job's subprocess or aborts
+// its in-flight HTTP calls - then waits for that cancellation to
+// actually unwind before returning ctx.Err(), so callers know the drain was
+// forced rather than clean. It does not wait for the (permanently idle
+// between jobs) worker-pool goroutines themselves to exit.
+func (t *Trigger) Wait(ctx context.Context) error {
+	done := make(chan struct{})
 	go func() {
-		defer close(resultCh)
-		result := t.execute(ctx, feedback)
-		resultCh <- result
+		t.wg.Wait()
+		close(done)
 	}()
 
-	return resultCh
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		t.mu.Lock()
+		cancels := make([]context.CancelFunc, 0, len(t.cancelRunning))
+		for _, cancel := range t.cancelRunning {
+			cancels = append(cancels, cancel)
+		}
+		t.mu.Unlock()
+
+		for _, cancel := range cancels {
+			cancel()
+		}
+		<-done
+		return ctx.Err()
+	}
 }
 
-// execute runs the actual business logic of the analysis.
-// It manages the lock to ensure only one analysis runs at a time (Singleton Worker).
-func (t *Trigger) execute(ctx context.Context, feedback *model.Feedback) Result {
-	result := Result{
-		Triggered: true,
-		StartedAt: time.Now(),
+// metricsLoop periodically refreshes the gauges that only make sense as a
+// snapshot (active job count, cooldown remaining, container health) rather
+// than something to push on every state change.
+func (t *Trigger) metricsLoop() {
+	ticker := time.NewTicker(t.config.MetricsPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		t.reportMetrics()
 	}
+}
 
-	// Critical Section: Acquire Lock
+// reportMetrics snapshots current trigger state into t.collector's gauges.
+func (t *Trigger) reportMetrics() {
 	t.mu.Lock()
-	if t.isRunning {
-		t.mu.Unlock()
-		result.Success = false
-		result.Message = "already running"
-		return result
+	running := len(t.cancelRunning)
+	t.mu.Unlock()
+
+	cooldownRemaining, err := t.locker.CooldownRemaining(t.config.Cooldown)
+	if err != nil {
+		log.Printf("[selfhealing] failed to check cooldown for metrics: %v", err)
+		cooldownRemaining = 0
+	}
+
+	t.collector.SetSelfHealingRunning(running)
+	t.collector.SetSelfHealingCooldownSeconds(cooldownRemaining.Seconds())
+	if t.executor != nil {
+		t.collector.SetSelfHealingContainerUp(t.backendLabel(), t.executor.HealthCheck() == nil)
+	}
+}
+
+// backendLabel identifies the opencode executor backend for metrics/status
+// output: the container name for docker/podman, the namespace for k8s, or
+// the API address for nomad, whichever config.Executor actually uses.
+func (t *Trigger) backendLabel() string {
+	switch t.config.Executor {
+	case "k8s":
+		return t.config.KubernetesNamespace
+	case "nomad":
+		return t.config.NomadAddr
+	default:
+		return t.config.ContainerName
 	}
-	t.isRunning = true
-	t.lastRun = time.Now()
+}
+
+// workerLoop is one member of the bounded worker pool: it repeatedly claims
+// and runs the oldest eligible queued job, falling back to a periodic poll
+// (in case a wake signal is ever missed, e.g. a retry requeued by another
+// worker) when there's nothing to claim.
+func (t *Trigger) workerLoop() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if t.claimAndRun() {
+			// More work may be waiting; loop immediately instead of
+			// sleeping until the next wake/tick.
+			continue
+		}
+
+		select {
+		case <-t.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// claimAndRun claims one queued job and runs it to completion, reporting
+// whether a job was actually claimed so workerLoop knows whether to poll
+// again immediately.
+func (t *Trigger) claimAndRun() bool {
+	job, err := t.store.ClaimNextJob()
+	if err != nil {
+		log.Printf("[selfhealing] failed to claim next job: %v", err)
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	t.wg.Add(1)
+	defer t.wg.Done()
+
+	feedback, err := t.store.GetByID(job.FeedbackID)
+	if err != nil || feedback == nil {
+		t.finish(job, Result{
+			Triggered:   true,
+			RequestID:   job.RequestID,
+			Message:     "feedback record not found",
+			Error:       fmt.Sprintf("failed to load feedback #%d: %v", job.FeedbackID, err),
+			StartedAt:   time.Now(),
+			CompletedAt: time.Now(),
+		})
+		return true
+	}
+
+	t.runJob(job, feedback)
+	return true
+}
+
+// runJob executes a single claimed job against feedback, then either
+// finishes it (success, or failure with no retries left) or requeues it for
+// a backoff retry.
+func (t *Trigger) runJob(job *model.Job, feedback *model.Feedback) {
+	ctx := requestid.WithValue(context.Background(), job.RequestID)
+	ctx, cancel := context.WithCancel(ctx)
+
+	t.mu.Lock()
+	t.cancelRunning[job.ID] = cancel
 	t.mu.Unlock()
 
-	// Ensure we release the lock when done, no matter what happens (panic/return).
+	if err := t.locker.RecordRun(time.Now()); err != nil {
+		log.Printf("[selfhealing] failed to record run time, cooldown may not hold fleet-wide: %v", err)
+	}
+
 	defer func() {
 		t.mu.Lock()
-		t.isRunning = false
+		delete(t.cancelRunning, job.ID)
 		t.mu.Unlock()
+		cancel()
 	}()
 
-	log.Printf("[selfhealing] Starting %s analysis for feedback #%d: %s", t.config.Mode, feedback.ID, feedback.Title)
+	result := Result{
+		Triggered: true,
+		RequestID: job.RequestID,
+		StartedAt: time.Now(),
+	}
+
+	log.Printf("[selfhealing] request_id=%s Starting %s analysis for feedback #%d: %s (job #%d, attempt %d/%d)",
+		result.RequestID, t.config.Mode, feedback.ID, feedback.Title, job.ID, job.Attempts, t.config.MaxAttempts)
+	t.hub.Publish(feedback.ID, StageAnalyzing,
+		fmt.Sprintf("running %s analysis (attempt %d/%d)", t.config.Mode, job.Attempts, t.config.MaxAttempts))
 
 	if t.config.DryRun {
 		result.Success = true
 		result.Message = fmt.Sprintf("dry run - would execute %s", t.config.Mode)
 		result.Output = fmt.Sprintf("Feedback: %s - %s", feedback.Title, feedback.Description)
 		result.CompletedAt = time.Now()
-		return result
+		t.finish(job, result)
+		return
 	}
 
 	var output string
+	var usage Usage
 	var err error
 
 	// Strategy Pattern: Choose the execution engine.
 	if t.config.Mode == "opencode" {
-		// External Agent: Call Docker
-		output, err = t.runOpenCode(ctx, feedback)
+		if t.executor == nil {
+			err = fmt.Errorf("opencode executor not configured")
+		} else {
+			output, err = t.runOpencodeExecutor(ctx, feedback)
+		}
 	} else {
-		// Internal Agent: Call LLM API
-		output, err = t.runAnalyze(ctx, feedback)
+		output, usage, err = t.runAnalyze(ctx, feedback)
 	}
 
 	result.CompletedAt = time.Now()
 	result.Output = output
+	result.Usage = usage
 
 	if err != nil {
 		result.Success = false
 		result.Error = err.Error()
 		result.Message = fmt.Sprintf("%s execution failed", t.config.Mode)
-		log.Printf("[selfhealing] Failed: %v", err)
-		return result
+		log.Printf("[selfhealing] job #%d failed: %v", job.ID, err)
+		t.retryOrFinish(job, result)
+		return
 	}
 
 	// Success Path
 	result.Success = true
 	result.Message = "analysis completed"
 	if t.config.Mode == "opencode" {
-		// Parse the unstructured text log to find structured data (PR URLs).
-		t.parseOutput(output, &result)
+		t.parseOutput(output, &result, func(event Event) {
+			log.Printf("[selfhealing] job #%d event: %s", job.ID, event.Type)
+			t.publishEvent(feedback.ID, event)
+		})
 	}
 
-	log.Printf("[selfhealing] Completed: %s", result.Message)
-	
+	log.Printf("[selfhealing] job #%d completed: %s", job.ID, result.Message)
+
 	// Logging (truncated to avoid spamming stdout)
 	if len(output) > 500 {
 		log.Printf("[selfhealing] Analysis (truncated):\n%s...", output[:500])
@@ -253,53 +644,141 @@ func (t *Trigger) execute(ctx context.Context, feedback *model.Feedback) Result
 		log.Printf("[selfhealing] PR created: %s", result.PRURL)
 	}
 
-	return result
-}
+	if result.Output != "" {
+		if err := t.store.UpdateAnalysis(feedback.ID, result.Output); err != nil {
+			log.Printf("[selfhealing] failed to store analysis for feedback #%d: %v", feedback.ID, err)
+		}
+	}
 
-// runOpenCode shells out to a script that pipes data into a Docker container.
-// This is used for "Full Autonomy" where the agent needs a sandbox to run git/tests.
-func (t *Trigger) runOpenCode(ctx context.Context, feedback *model.Feedback) (string, error) {
-	// Create context with timeout to prevent hanging forever.
-	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
-	defer cancel()
+	t.finish(job, result)
+}
 
-	// IPC (Inter-Process Communication): Serialize data to JSON to pass to the script.
-	feedbackJSON, err := json.Marshal(map[string]interface{}{
-		"id":          feedback.ID,
-		"title":       feedback.Title,
-		"description": feedback.Description,
-		"type":        feedback.Type,
-		"url":         feedback.URL,
-		"consoleLogs": feedback.ConsoleLogs,
-	})
+// runOpencodeExecutor acquires t.locker's running lock before driving
+// t.executor, so two replicas never send feedback to the same shared
+// container/job backend at once, refreshes that lock via a heartbeat
+// goroutine for as long as the executor runs (so it survives longer than
+// Timeout wouldn't otherwise cover), and releases it once Run returns.
+func (t *Trigger) runOpencodeExecutor(ctx context.Context, feedback *model.Feedback) (string, error) {
+	acquired, err := t.locker.TryLockRunning(t.config.Timeout)
 	if err != nil {
-		return "", fmt.Errorf("failed to serialize feedback: %v", err)
+		log.Printf("[selfhealing] failed to acquire running lock, proceeding without it: %v", err)
+		acquired = true
+	}
+	if !acquired {
+		return "", fmt.Errorf("another replica is currently running an opencode analysis")
 	}
 
-	// `exec.CommandContext` kills the process if ctx is cancelled/times out.
-	cmd := exec.CommandContext(ctx, t.config.TriggerScript, string(feedbackJSON))
-	// Pass configuration via Environment Variables to the script
-	cmd.Env = append(os.Environ(), "OPENCODE_CONTAINER="+t.config.ContainerName)
+	heartbeatDone := make(chan struct{})
+	go t.heartbeatRunningLock(heartbeatDone)
+	defer func() {
+		close(heartbeatDone)
+		if err := t.locker.UnlockRunning(); err != nil {
+			log.Printf("[selfhealing] failed to release running lock: %v", err)
+		}
+	}()
+
+	return t.executor.Run(ctx, feedback)
+}
 
-	// Capture stdout/stderr for debugging
-	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
+// heartbeatRunningLock refreshes t.locker's running lock at half its TTL so
+// it never expires out from under a job that's still executing, until done
+// is closed. If the holding process crashes before that, the lock's TTL
+// (unrefreshed) still expires on its own, which is the whole point.
+func (t *Trigger) heartbeatRunningLock(done <-chan struct{}) {
+	interval := t.config.Timeout / 2
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-	err = cmd.Run()
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return "", fmt.Errorf("timeout after %v", t.config.Timeout)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := t.locker.RefreshRunning(t.config.Timeout); err != nil {
+				log.Printf("[selfhealing] failed to refresh running lock: %v", err)
+			}
 		}
-		return "", fmt.Errorf("%v: %s", err, stderr.String())
 	}
+}
+
+// finish persists a terminal outcome (done, or dead after exhausting
+// retries) and delivers result to the TriggerAsync caller that enqueued
+// this job, if one is still waiting.
+func (t *Trigger) finish(job *model.Job, result Result) {
+	state := model.JobStateDone
+	resultLabel := "success"
+	if !result.Success {
+		state = model.JobStateDead
+		resultLabel = "failure"
+	}
+	t.collector.ObserveSelfHealingJob(job.Mode, resultLabel, result.CompletedAt.Sub(result.StartedAt))
 
-	return stdout.String(), nil
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("[selfhealing] failed to serialize result for job #%d: %v", job.ID, err)
+	}
+	if err := t.store.FinishJob(job.ID, state, string(resultJSON), result.Error); err != nil {
+		log.Printf("[selfhealing] failed to persist completion of job #%d: %v", job.ID, err)
+	}
+
+	stage, message := StageDone, result.Message
+	if !result.Success {
+		stage, message = StageFailed, result.Error
+	}
+	t.hub.Publish(job.FeedbackID, stage, message)
+
+	t.deliver(job.ID, result)
+}
+
+// retryOrFinish requeues a failed job with exponential backoff if it hasn't
+// exceeded MaxAttempts, or gives up and finishes it as dead otherwise. No
+// Result is delivered to a waiting TriggerAsync caller on a retry - only
+// the eventual terminal outcome is, so callers never see more than one
+// Result per job.
+func (t *Trigger) retryOrFinish(job *model.Job, result Result) {
+	if job.Attempts >= t.config.MaxAttempts {
+		log.Printf("[selfhealing] job #%d exhausted %d attempts, giving up", job.ID, t.config.MaxAttempts)
+		t.finish(job, result)
+		return
+	}
+
+	backoff := t.config.RetryBaseDelay * time.Duration(1<<uint(job.Attempts-1))
+	nextAttempt := time.Now().Add(backoff)
+	log.Printf("[selfhealing] job #%d will retry in %v (attempt %d/%d): %s",
+		job.ID, backoff, job.Attempts, t.config.MaxAttempts, result.Error)
+
+	if err := t.store.RequeueJob(job.ID, nextAttempt, result.Error); err != nil {
+		log.Printf("[selfhealing] failed to requeue job #%d, giving up: %v", job.ID, err)
+		t.finish(job, result)
+	}
+}
+
+// deliver sends result to the TriggerAsync caller waiting on jobID, if any,
+// and closes its channel so the caller never sees more than one Result.
+// Jobs with no registered waiter (e.g. recovered after a crash, enqueued by
+// a process that has since exited) are simply dropped here; their outcome
+// is still persisted and visible via GetJobByID/ListJobs.
+func (t *Trigger) deliver(jobID int64, result Result) {
+	t.mu.Lock()
+	ch, ok := t.waiters[jobID]
+	if ok {
+		delete(t.waiters, jobID)
+	}
+	t.mu.Unlock()
+
+	if !ok {
+		return
+	}
+	ch <- result
+	close(ch)
 }
 
 // runAnalyze runs the native Go-based LLM agent.
 // This is used for "Read-Only" analysis where we just want a bug report.
-func (t *Trigger) runAnalyze(ctx context.Context, feedback *model.Feedback) (string, error) {
+func (t *Trigger) runAnalyze(ctx context.Context, feedback *model.Feedback) (string, Usage, error) {
 	ctx, cancel := context.WithTimeout(ctx, t.config.Timeout)
 	defer cancel()
 
@@ -313,50 +792,149 @@ func (t *Trigger) runAnalyze(ctx context.Context, feedback *model.Feedback) (str
 
 	log.Printf("[selfhealing] Source directory for file access: %s", sourceDir)
 
-	analyzer := NewLLMAnalyzer(
-		t.config.LLMAPIKey,
-		t.config.LLMBaseURL,
-		t.config.LLMModel,
-		sourceDir,
-	)
+	providers := t.buildProviders()
+
+	var analyzer *LLMAnalyzer
+	if t.config.LLMEmbeddingModel != "" {
+		analyzer = NewLLMAnalyzerWithIndex(
+			providers,
+			sourceDir,
+			t.config.LLMAPIKey,
+			t.config.LLMBaseURL,
+			t.config.LLMEmbeddingModel,
+			t.config.LLMEmbeddingK,
+		)
+	} else {
+		analyzer = NewLLMAnalyzer(providers, sourceDir)
+	}
+	analyzer.Budget = t.buildBudget()
 
 	return analyzer.Analyze(ctx, feedback)
 }
 
-// parseOutput scrapes stdout for artifacts like PR URLs or branch names.
-func (t *Trigger) parseOutput(output string, result *Result) {
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
+// buildProviders assembles the ordered provider fallback chain from
+// configuration: the primary OpenAI-compatible provider first, then any
+// optional fallbacks whose credentials/URL are actually configured.
+func (t *Trigger) buildProviders() []Provider {
+	var providers []Provider
+	if t.config.LLMAPIKey != "" {
+		providers = append(providers, NewOpenAIProvider(t.config.LLMAPIKey, t.config.LLMBaseURL, t.config.LLMModel))
+	}
+	if t.config.AnthropicAPIKey != "" {
+		providers = append(providers, NewAnthropicProvider(t.config.AnthropicAPIKey, t.config.AnthropicModel))
+	}
+	if t.config.OllamaBaseURL != "" {
+		providers = append(providers, NewOllamaProvider(t.config.OllamaBaseURL, t.config.OllamaModel))
+	}
+	return providers
+}
+
+// buildBudget assembles the token/dollar budget for a single Analyze call,
+// with a small built-in pricing table covering the providers buildProviders
+// can construct. Returns nil (unlimited) if no cap is configured.
+func (t *Trigger) buildBudget() *Budget {
+	if t.config.BudgetMaxInputTokens <= 0 && t.config.BudgetMaxOutputTokens <= 0 && t.config.BudgetMaxUSD <= 0 {
+		return nil
+	}
+	return &Budget{
+		MaxInputTokens:  t.config.BudgetMaxInputTokens,
+		MaxOutputTokens: t.config.BudgetMaxOutputTokens,
+		MaxUSD:          t.config.BudgetMaxUSD,
+		Pricing: map[string]ModelPricing{
+			fmt.Sprintf("openai:%s", t.config.LLMModel):          {InputPerMille: 0.00059, OutputPerMille: 0.00079}, // Groq Llama 3.3 70B, approximate
+			fmt.Sprintf("anthropic:%s", t.config.AnthropicModel): {InputPerMille: 0.003, OutputPerMille: 0.015},     // Claude 3.5 Sonnet, approximate
+			fmt.Sprintf("ollama:%s", t.config.OllamaModel):       {InputPerMille: 0, OutputPerMille: 0},             // Local inference - no per-token cost
+		},
+	}
+}
+
+// publishEvent maps one decoded opencode Event onto the stage vocabulary
+// t.hub's SSE subscribers understand.
+func (t *Trigger) publishEvent(feedbackID int64, event Event) {
+	switch event.Type {
+	case "files_changed":
+		t.hub.Publish(feedbackID, StagePatchGenerated, fmt.Sprintf("changed: %s", strings.Join(event.Paths, ", ")))
+	case "pr_created":
+		t.hub.Publish(feedbackID, StagePROpened, fmt.Sprintf("opened %s", event.URL))
+	case "tests":
+		t.hub.Publish(feedbackID, StageLog, fmt.Sprintf("tests: %d passed, %d failed", event.Passed, event.Failed))
+	case "log":
+		t.hub.Publish(feedbackID, StageLog, event.Message)
+	}
+}
+
+// parseOutput decodes output line by line as the opencode container's NDJSON
+// event protocol (see Event): one JSON object per line, discriminated by its
+// "event" field. A line that isn't valid JSON (or has no "event" field)
+// falls back to parseOutputHeuristic, the original substring-scraping
+// parser, so older agents that only print plain log lines still populate
+// PR/branch info. onEvent is called with every successfully decoded Event,
+// in the order parsed, so a caller doesn't have to wait for the whole
+// output before observing progress; it may be nil.
+func (t *Trigger) parseOutput(output string, result *Result, onEvent EventCallback) {
+	for _, line := range strings.Split(output, "\n") {
 		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
 
-		// Regex-like parsing to find GitHub PR URLs
-		if strings.Contains(line, "github.com") && strings.Contains(line, "/pull/") {
-			words := strings.Fields(line)
-			for _, word := range words {
-				if strings.Contains(word, "github.com") && strings.Contains(word, "/pull/") {
-					result.PRURL = strings.Trim(word, "()[]<>")
-					
-					// Extract the numeric ID from the URL
-					parts := strings.Split(result.PRURL, "/pull/")
-					if len(parts) > 1 {
-						numStr := strings.Split(parts[1], "/")[0]
-						numStr = strings.TrimSpace(numStr)
-						fmt.Sscanf(numStr, "%d", &result.PRNumber)
-					}
-					break
-				}
+		var event Event
+		if err := json.Unmarshal([]byte(line), &event); err != nil || event.Type == "" {
+			t.parseOutputHeuristic(line, result)
+			continue
+		}
+
+		result.Events = append(result.Events, event)
+		if onEvent != nil {
+			onEvent(event)
+		}
+
+		switch event.Type {
+		case "pr_created":
+			result.PRURL = event.URL
+			result.PRNumber = event.Number
+			if event.Branch != "" {
+				result.Branch = event.Branch
 			}
+		case "files_changed":
+			result.FilesChanged = append(result.FilesChanged, event.Paths...)
+		case "tests":
+			result.Tests = &TestSummary{Passed: event.Passed, Failed: event.Failed}
 		}
+	}
+}
 
-		// Look for branch information
-		if strings.HasPrefix(line, "Branch:") {
-			result.Branch = strings.TrimSpace(strings.TrimPrefix(line, "Branch:"))
-		} else if strings.HasPrefix(line, "fix/") || strings.HasPrefix(line, "feature/") {
-			if result.Branch == "" {
-				result.Branch = strings.Fields(line)[0]
+// parseOutputHeuristic is the pre-NDJSON fallback: substring-scrapes a
+// single line for a GitHub PR URL or a "Branch:"/fix-.../feature-... prefix,
+// for opencode agent versions that predate the structured event protocol.
+func (t *Trigger) parseOutputHeuristic(line string, result *Result) {
+	// Regex-like parsing to find GitHub PR URLs
+	if strings.Contains(line, "github.com") && strings.Contains(line, "/pull/") {
+		words := strings.Fields(line)
+		for _, word := range words {
+			if strings.Contains(word, "github.com") && strings.Contains(word, "/pull/") {
+				result.PRURL = strings.Trim(word, "()[]<>")
+
+				// Extract the numeric ID from the URL
+				parts := strings.Split(result.PRURL, "/pull/")
+				if len(parts) > 1 {
+					numStr := strings.Split(parts[1], "/")[0]
+					numStr = strings.TrimSpace(numStr)
+					fmt.Sscanf(numStr, "%d", &result.PRNumber)
+				}
+				break
 			}
 		}
 	}
+
+	// Look for branch information
+	if strings.HasPrefix(line, "Branch:") {
+		result.Branch = strings.TrimSpace(strings.TrimPrefix(line, "Branch:"))
+	} else if strings.HasPrefix(line, "fix/") || strings.HasPrefix(line, "feature/") {
+		if result.Branch == "" {
+			result.Branch = strings.Fields(line)[0]
+		}
+	}
 }
 
 // isAdmin checks if the user is in the allow-list.
@@ -393,6 +971,48 @@ func getEnvOrDefault(key, defaultValue string) string {
 	return defaultValue
 }
 
+// Helper: integer environment variable retrieval, falling back to
+// defaultValue if unset or unparsable.
+func getEnvIntOrDefault(key string, defaultValue int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.Atoi(v)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// Helper: float environment variable retrieval, falling back to
+// defaultValue if unset or unparsable.
+func getEnvFloatOrDefault(key string, defaultValue float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
+// Helper: duration environment variable retrieval, falling back to
+// defaultValue if unset or unparsable.
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return defaultValue
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		return defaultValue
+	}
+	return parsed
+}
+
 // Helper: parse CSV string to slice.
 func parseCSV(s string) []string {
 	if s == "" {
@@ -419,20 +1039,27 @@ func parseAllowedTypes(s string) []string {
 // Status returns a snapshot of the internal state (Health Check pattern).
 func (t *Trigger) Status() map[string]interface{} {
 	t.mu.Lock()
-	defer t.mu.Unlock()
+	activeJobs := len(t.cancelRunning)
+	t.mu.Unlock()
 
 	status := map[string]interface{}{
-		"enabled":        t.config.Enabled,
-		"is_running":     t.isRunning,
-		"container_name": t.config.ContainerName,
+		"enabled":     t.config.Enabled,
+		"active_jobs": activeJobs,
 	}
 
-	if !t.lastRun.IsZero() {
-		status["last_run"] = t.lastRun
-		status["cooldown_remaining"] = (t.config.Cooldown - time.Since(t.lastRun)).Round(time.Second).String()
+	if t.config.Mode == "opencode" {
+		status["executor"] = t.config.Executor
+		status["container_name"] = t.backendLabel()
+		if t.executor != nil {
+			status["container_running"] = t.executor.HealthCheck() == nil
+		}
 	}
 
-	status["container_running"] = t.isContainerRunning()
+	if remaining, err := t.locker.CooldownRemaining(t.config.Cooldown); err != nil {
+		log.Printf("[selfhealing] failed to check cooldown for status: %v", err)
+	} else if remaining > 0 {
+		status["cooldown_remaining"] = remaining.Round(time.Second).String()
+	}
 
 	if _, err := os.Stat(t.config.TriggerScript); err == nil {
 		status["trigger_script"] = t.config.TriggerScript
@@ -441,19 +1068,27 @@ func (t *Trigger) Status() map[string]interface{} {
 	return status
 }
 
-// Validate ensures configuration is sane before we start.
+// Validate ensures configuration is sane before we start, including the
+// chosen opencode executor backend's own prerequisites (trigger script
+// present, podman binary on PATH, Kubernetes config loadable, k8s/Nomad
+// settings set - see buildExecutor and each Executor's Prepare). It does
+// not contact the cluster or the Nomad API.
 func (c *Config) Validate() error {
 	if !c.Enabled {
 		return nil
 	}
 
 	if c.Mode == "opencode" {
-		if _, err := os.Stat(c.TriggerScript); os.IsNotExist(err) {
-			return fmt.Errorf("trigger script not found: %s", c.TriggerScript)
-		}
 		if len(c.AdminEmails) == 0 {
 			return fmt.Errorf("ADMIN_EMAILS is required for opencode mode")
 		}
+		executor, err := buildExecutor(*c)
+		if err != nil {
+			return err
+		}
+		if err := executor.Prepare(); err != nil {
+			return fmt.Errorf("executor %q not ready: %w", c.Executor, err)
+		}
 	} else {
 		if c.LLMAPIKey == "" {
 			return fmt.Errorf("LLM_API_KEY is required for analyze mode")
@@ -461,4 +1096,4 @@ func (c *Config) Validate() error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}